@@ -0,0 +1,217 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+var formatPlaceholder = regexp.MustCompile(`\$Format:([^$]*)\$`)
+
+// ArchiveReport describes the changes made by ApplyArchiveAttributes.
+type ArchiveReport struct {
+	// Removed is the number of export-ignore files and directories that were
+	// removed.
+	Removed int
+
+	// Expanded is the number of export-subst files whose placeholders were
+	// expanded.
+	Expanded int
+}
+
+// ApplyArchiveAttributes makes the tracked files below dir look like the
+// output of git archive: the files and directories with the export-ignore
+// attribute are removed and the $Format:...$ placeholders of the files with
+// the export-subst attribute are expanded for HEAD.
+func ApplyArchiveAttributes(executable Executable, logger scribe.Emitter, dir string) (ArchiveReport, error) {
+	output, err := runGitRaw(executable, logger, dir, "ls-files", "-z")
+	if err != nil {
+		return ArchiveReport{}, err
+	}
+
+	// The attributes of a directory apply to all of its contents in git
+	// archive, so the directories are checked along with the files
+	paths := map[string]bool{}
+	for _, file := range strings.Split(output, "\x00") {
+		for path := file; path != "" && path != "." && !paths[path]; path = filepath.Dir(path) {
+			paths[path] = true
+		}
+	}
+
+	if len(paths) == 0 {
+		return ArchiveReport{}, nil
+	}
+
+	var sorted []string
+	for path := range paths {
+		sorted = append(sorted, path)
+	}
+	sort.Strings(sorted)
+
+	attributes, err := checkAttributes(executable, logger, dir, sorted, "export-ignore", "export-subst")
+	if err != nil {
+		return ArchiveReport{}, err
+	}
+
+	var (
+		report  ArchiveReport
+		removed []string
+		substs  []string
+	)
+	for _, path := range sorted {
+		if underAny(path, removed) {
+			continue
+		}
+
+		if attributes[path]["export-ignore"] == "set" {
+			err = os.RemoveAll(filepath.Join(dir, path))
+			if err != nil {
+				return ArchiveReport{}, fmt.Errorf("failed to remove %s: %w", path, err)
+			}
+
+			removed = append(removed, path)
+			report.Removed++
+			continue
+		}
+
+		if attributes[path]["export-subst"] == "set" {
+			substs = append(substs, path)
+		}
+	}
+
+	expanded, err := expandPlaceholders(executable, logger, dir, substs)
+	if err != nil {
+		return ArchiveReport{}, err
+	}
+	report.Expanded = expanded
+
+	return report, nil
+}
+
+// checkAttributes returns the value of the given attributes for every path.
+func checkAttributes(executable Executable, logger scribe.Emitter, dir string, paths []string, attributes ...string) (map[string]map[string]string, error) {
+	args := append([]string{"check-attr", "-z", "--stdin"}, attributes...)
+
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	err := executable.Execute(pexec.Execution{
+		Args:   args,
+		Dir:    dir,
+		Stdin:  strings.NewReader(strings.Join(paths, "\x00") + "\x00"),
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		logger.Detail(stderr.String())
+		return nil, fmt.Errorf("failed to execute 'git %s': %w", strings.Join(args, " "), err)
+	}
+
+	// The output is a sequence of NUL terminated path, attribute and value
+	// triples
+	fields := strings.Split(stdout.String(), "\x00")
+	values := map[string]map[string]string{}
+	for i := 0; i+2 < len(fields); i += 3 {
+		path, attribute, value := fields[i], fields[i+1], fields[i+2]
+		if values[path] == nil {
+			values[path] = map[string]string{}
+		}
+		values[path][attribute] = value
+	}
+
+	return values, nil
+}
+
+// expandPlaceholders replaces the $Format:...$ placeholders in the given
+// files with the pretty format of HEAD and returns the number of files that
+// contained any. Every distinct placeholder is formatted by a single git log.
+func expandPlaceholders(executable Executable, logger scribe.Emitter, dir string, paths []string) (int, error) {
+	contents := map[string][]byte{}
+	var formats []string
+	seen := map[string]bool{}
+	for _, path := range paths {
+		info, err := os.Lstat(filepath.Join(dir, path))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return 0, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, path))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		matches := formatPlaceholder.FindAllSubmatch(content, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		contents[path] = content
+		for _, match := range matches {
+			if format := string(match[1]); !seen[format] {
+				seen[format] = true
+				formats = append(formats, format)
+			}
+		}
+	}
+
+	if len(contents) == 0 {
+		return 0, nil
+	}
+
+	output, err := runGitRaw(executable, logger, dir, "log", "-1", "--no-show-signature", "--format=format:"+strings.Join(formats, "%x00"), "HEAD")
+	if err != nil {
+		return 0, err
+	}
+
+	values := strings.Split(output, "\x00")
+	if len(values) != len(formats) {
+		return 0, fmt.Errorf("failed to expand export-subst placeholders: expected %d values, got %d", len(formats), len(values))
+	}
+
+	expansions := map[string][]byte{}
+	for i, format := range formats {
+		expansions[format] = []byte(values[i])
+	}
+
+	for path, content := range contents {
+		content = formatPlaceholder.ReplaceAllFunc(content, func(placeholder []byte) []byte {
+			return expansions[string(placeholder[len("$Format:"):len(placeholder)-1])]
+		})
+
+		info, err := os.Stat(filepath.Join(dir, path))
+		if err != nil {
+			return 0, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		err = os.WriteFile(filepath.Join(dir, path), content, info.Mode().Perm())
+		if err != nil {
+			return 0, fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+
+	return len(contents), nil
+}
+
+// underAny reports whether path is below any of the given directories.
+func underAny(path string, dirs []string) bool {
+	for _, dir := range dirs {
+		if strings.HasPrefix(path, dir+"/") {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,187 @@
+package git_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/git/fakes"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testArchive(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		executable *fakes.Executable
+		executions []pexec.Execution
+		stdin      string
+		outputs    map[string]string
+		logger     scribe.Emitter
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(workingDir, "fixtures", "b"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, ".gitattributes"), []byte("fixtures export-ignore\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "fixtures", "a"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "fixtures", "b", "c"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "keep.txt"), []byte("$Format:%H$"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "other.txt"), []byte("no placeholders"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "test.txt"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "version.sh"), []byte("VERSION=$Format:%H$ DATE=$Format:%cs$ SHA=$Format:%H$"), 0755)).To(Succeed())
+		Expect(os.Symlink("keep.txt", filepath.Join(workingDir, "link"))).To(Succeed())
+
+		outputs = map[string]string{
+			"ls-files -z": ".gitattributes\x00fixtures/a\x00fixtures/b/c\x00keep.txt\x00link\x00other.txt\x00test.txt\x00version.sh\x00",
+			"check-attr -z --stdin export-ignore export-subst": strings.Join([]string{
+				"fixtures", "export-ignore", "set",
+				"fixtures/a", "export-ignore", "unspecified",
+				"keep.txt", "export-subst", "unset",
+				"link", "export-subst", "set",
+				"other.txt", "export-subst", "set",
+				"test.txt", "export-ignore", "set",
+				"version.sh", "export-subst", "set",
+			}, "\x00") + "\x00",
+			"log -1 --no-show-signature --format=format:%H%x00%cs HEAD": "some-sha\x002023-01-02",
+		}
+
+		executions = nil
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			executions = append(executions, execution)
+			if execution.Stdin != nil {
+				content, err := io.ReadAll(execution.Stdin)
+				Expect(err).NotTo(HaveOccurred())
+				stdin = string(content)
+			}
+
+			fmt.Fprint(execution.Stdout, outputs[strings.Join(execution.Args, " ")])
+			return nil
+		}
+
+		logger = scribe.NewEmitter(bytes.NewBuffer(nil))
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("ApplyArchiveAttributes", func() {
+		it("removes the export-ignore paths and expands the export-subst placeholders", func() {
+			report, err := git.ApplyArchiveAttributes(executable, logger, workingDir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(report).To(Equal(git.ArchiveReport{Removed: 2, Expanded: 1}))
+
+			Expect(executions).To(HaveLen(3))
+			for _, execution := range executions {
+				Expect(execution.Dir).To(Equal(workingDir))
+			}
+			Expect(stdin).To(Equal(".gitattributes\x00fixtures\x00fixtures/a\x00fixtures/b\x00fixtures/b/c\x00keep.txt\x00link\x00other.txt\x00test.txt\x00version.sh\x00"))
+
+			Expect(filepath.Join(workingDir, "fixtures")).NotTo(BeADirectory())
+			Expect(filepath.Join(workingDir, "test.txt")).NotTo(BeAnExistingFile())
+
+			content, err := os.ReadFile(filepath.Join(workingDir, "version.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("VERSION=some-sha DATE=2023-01-02 SHA=some-sha"))
+
+			info, err := os.Stat(filepath.Join(workingDir, "version.sh"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+			content, err = os.ReadFile(filepath.Join(workingDir, "keep.txt"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("$Format:%H$"))
+
+			target, err := os.Readlink(filepath.Join(workingDir, "link"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(target).To(Equal("keep.txt"))
+		})
+
+		context("when no file has the export-subst attribute", func() {
+			it.Before(func() {
+				outputs["check-attr -z --stdin export-ignore export-subst"] = "test.txt\x00export-ignore\x00set\x00"
+			})
+
+			it("does not read the commit", func() {
+				report, err := git.ApplyArchiveAttributes(executable, logger, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report).To(Equal(git.ArchiveReport{Removed: 1}))
+
+				Expect(executions).To(HaveLen(2))
+			})
+		})
+
+		context("when there are no tracked files", func() {
+			it.Before(func() {
+				outputs["ls-files -z"] = ""
+			})
+
+			it("does nothing", func() {
+				report, err := git.ApplyArchiveAttributes(executable, logger, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report).To(Equal(git.ArchiveReport{}))
+
+				Expect(executions).To(HaveLen(1))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when git fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(pexec.Execution) error {
+						return errors.New("some-error")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := git.ApplyArchiveAttributes(executable, logger, workingDir)
+					Expect(err).To(MatchError("failed to execute 'git ls-files -z': some-error"))
+				})
+			})
+
+			context("when the attributes cannot be checked", func() {
+				it.Before(func() {
+					stub := executable.ExecuteCall.Stub
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						if execution.Args[0] == "check-attr" {
+							return errors.New("some-error")
+						}
+						return stub(execution)
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := git.ApplyArchiveAttributes(executable, logger, workingDir)
+					Expect(err).To(MatchError("failed to execute 'git check-attr -z --stdin export-ignore export-subst': some-error"))
+				})
+			})
+
+			context("when the placeholders are not all formatted", func() {
+				it.Before(func() {
+					outputs["log -1 --no-show-signature --format=format:%H%x00%cs HEAD"] = "some-sha"
+				})
+
+				it("returns an error", func() {
+					_, err := git.ApplyArchiveAttributes(executable, logger, workingDir)
+					Expect(err).To(MatchError("failed to expand export-subst placeholders: expected 2 values, got 1"))
+				})
+			})
+		})
+	})
+}
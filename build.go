@@ -1,15 +1,17 @@
 package git
 
 import (
-	"bytes"
+	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
-	"strings"
+	"strconv"
+	"time"
 
 	"github.com/paketo-buildpacks/packit/v2"
-	"github.com/paketo-buildpacks/packit/v2/fs"
 	"github.com/paketo-buildpacks/packit/v2/pexec"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
 )
 
 const (
@@ -24,62 +26,658 @@ type Executable interface {
 
 //go:generate faux --interface CredentialManager --output fakes/credential_manager.go
 type CredentialManager interface {
-	Setup(workingDir, platformPath string) (err error)
+	Setup(workingDir, platformPath string, config Configuration) (err error)
 }
 
-func Build(executable Executable, credentialManager CredentialManager, logger scribe.Emitter) packit.BuildFunc {
+//go:generate faux --interface SignatureVerifier --output fakes/signature_verifier.go
+type SignatureVerifier interface {
+	Verify(workingDir, platformPath, mode string, verifyTags bool) (report SignatureReport, err error)
+}
+
+func Build(environment Environment, bindingResolver BindingResolver, executable Executable, credentialManager CredentialManager, signatureVerifier SignatureVerifier, logger scribe.Emitter) packit.BuildFunc {
 	return func(context packit.BuildContext) (packit.BuildResult, error) {
 		logger.Title("%s %s", context.BuildpackInfo.Name, context.BuildpackInfo.Version)
 
+		config, err := LoadConfiguration(environment)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
 		layer, err := context.Layers.Get(LayerNameGit)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		layer.Launch = true
-		layer.Build = true
+		source, clone, err := ResolveSource(config, bindingResolver, context.Platform.Path)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
 
-		exist, err := fs.Exists(filepath.Join(context.WorkingDir, ".git"))
+		supplementary, err := ResolveSupplementarySources(bindingResolver, context.Platform.Path)
 		if err != nil {
 			return packit.BuildResult{}, err
 		}
 
-		var buildResult packit.BuildResult
+		// The credentials are needed to fetch the sources, so they are
+		// configured before anything else when there are any
+		credentialsConfigured := clone || len(supplementary) > 0 || len(config.Mirrors) > 0
+		if credentialsConfigured {
+			err = credentialManager.Setup(context.WorkingDir, context.Platform.Path, config)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to configure given credentials: %w", err)
+			}
+		}
+
+		// The mirrors are set up first so that the sources are also fetched
+		// through them
+		var mirrorsLayer packit.Layer
+		if len(config.Mirrors) > 0 {
+			mirrorsLayer, err = context.Layers.Get(LayerNameGitMirrors)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Process("Updating git mirrors")
+			count, err := SetupMirrors(executable, logger, context.WorkingDir, mirrorsLayer.Path, config.Mirrors)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			logger.Subprocess("Fetching %d repository URL(s) through mirrors", count)
+			logger.Break()
+
+			mirrorsLayer.Cache = true
+		}
+
+		if clone {
+			err = cloneSource(executable, logger, context.WorkingDir, source)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+		}
+
+		repository, exist, err := FindRepository(context.WorkingDir, config.CeilingDirectory, config.SearchParents)
+		if err != nil {
+			return packit.BuildResult{}, err
+		}
+
+		var (
+			buildResult packit.BuildResult
+			collector   = NewMetadataCollector(executable, logger)
+			metadata    Metadata
+			fields      MetadataFields
+			root        = context.WorkingDir
+			found       = exist
+		)
+
 		if exist {
-			buffer := bytes.NewBuffer(nil)
-			args := []string{"rev-parse", "HEAD"}
-			err = executable.Execute(pexec.Execution{
-				Args:   args,
-				Dir:    context.WorkingDir,
-				Stdout: buffer,
-				Stderr: buffer,
-			})
+			logger.Process("Found git repository")
+			logger.Subprocess("Repository root: %s", repository.Root)
+			logger.Subprocess("App path: %s", repository.AppPath)
+
+			fields, err = RequestedMetadataFields(context.Plan.Entries)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if fields != nil {
+				logger.Subprocess("Requested metadata: %s", fields)
+			}
+			logger.Break()
+
+			// The workspace is cleaned before the metadata is collected so
+			// that the dirty flag describes the files that are built
+			if config.Clean != "" {
+				err = cleanWorkspace(executable, logger, config, context.WorkingDir)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			if config.SecretPolicy != SecretPolicyIgnore {
+				err = checkEmbeddedCredentials(logger, config, repository)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			metadata, err = collector.Collect(repository.Root, fields)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			metadata.Source = MetadataSourceGit
+			root = repository.Root
+
+			if config.ArchiveMode {
+				logger.Process("Applying export attributes")
+				report, err := ApplyArchiveAttributes(executable, logger, context.WorkingDir)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				logger.Subprocess("Removed %d export-ignore path(s)", report.Removed)
+				logger.Subprocess("Expanded placeholders in %d export-subst file(s)", report.Expanded)
+				logger.Break()
+			}
+
+			// The modification times are restored last as expanding the
+			// placeholders rewrites files
+			if config.RestoreMTimes != "" {
+				logger.Process("Restoring file modification times (%s)", config.RestoreMTimes)
+				count, err := RestoreModificationTimes(executable, logger, context.WorkingDir, config.RestoreMTimes)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				logger.Subprocess("Updated %d file(s)", count)
+				logger.Break()
+			}
+		} else {
+			metadata, found, err = FallbackMetadata(context.WorkingDir, environment)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			if found {
+				logger.Process("Found revision without a git repository")
+				logger.Subprocess("Source: %s", metadata.Source)
+				logger.Subprocess("Revision: %s", metadata.Revision)
+				logger.Break()
+			}
+		}
+
+		// The supplementary sources are cloned once the metadata of the
+		// application source has been collected so that they do not count as
+		// changes to it
+		var sourcesLayer packit.Layer
+		if len(supplementary) > 0 {
+			sourcesLayer, err = context.Layers.Get(LayerNameGitSources)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Process("Cloning supplementary sources")
+			metadata.Sources, err = CloneSupplementarySources(executable, logger, context.WorkingDir, sourcesLayer.Path, supplementary)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+			logger.Break()
+
+			sourcesLayer.Cache = true
+		}
+
+		// The tree is attested once nothing else changes the application
+		// source
+		if exist && config.TreeAttestation {
+			logger.Process("Attesting application source tree")
+			attestation, err := AttestTree(executable, logger, context.WorkingDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
+
+			logger.Subprocess("Tree: %s", attestation.Tree)
+			logger.Subprocess("HEAD tree: %s", attestation.HeadTree)
+			if !attestation.Matches {
+				logger.Subprocess("Warning: the application source differs from the tree of HEAD")
+			}
+			logger.Break()
+
+			metadata.Tree = &attestation
+		}
+
+		if found && config.CIContext {
+			if ci, ok := DetectCIContext(environment); ok {
+				logCIContext(logger, ci)
+				metadata.CI = &ci
+
+				// A detached HEAD, as checked out by most CI systems, does
+				// not name the branch that was built
+				if metadata.Branch == "" && fields.Has(MetadataFieldBranch) {
+					metadata.Branch = ci.SourceBranch
+				}
+			}
+		}
+
+		if found {
+			// Credentials embedded in the remote URL must not leak into the
+			// metadata, SBOM or labels
+			metadata.Remote = RedactCredentials(metadata.Remote)
+
+			var bindings []servicebindings.Binding
+			if exist && config.SignatureMode != SignatureModeOff {
+				bindings, err = bindingResolver.Resolve("git-signing-keys", "", context.Platform.Path)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
+
+			key, err := NewLayerKey(environment, fields, bindings, metadata)
 			if err != nil {
-				logger.Detail(buffer.String())
-				return packit.BuildResult{}, fmt.Errorf("failed to execute 'git rev-parse HEAD': %w", err)
+				return packit.BuildResult{}, err
 			}
 
-			revision := strings.TrimSpace(buffer.String())
+			var labels map[string]string
+			if key.Matches(layer) {
+				logger.Process("Reusing cached layer %s", layer.Path)
+				logger.Break()
+
+				labels = StoredLabels(layer)
 
-			layer.SharedEnv.Default("REVISION", revision)
+				if metadata.CI != nil {
+					err = refreshCIRun(&layer, config, key, labels, metadata)
+					if err != nil {
+						return packit.BuildResult{}, err
+					}
+				}
+			} else {
+				layer, err = layer.Reset()
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				metadataPath, err := WriteMetadata(layer.Path, metadata)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				err = WriteProvenance(layer.Path, metadata, root)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+
+				exportVariable(&layer, config, "REVISION", metadata.Revision)
+				exportVariable(&layer, config, "GIT_METADATA_FILE", metadataPath)
+
+				if config.SourceDateEpoch {
+					epoch := config.SourceDateEpochOverride
+					if epoch == "" && exist {
+						epoch, err = collector.CommitTimestamp(repository.Root)
+						if err != nil {
+							return packit.BuildResult{}, err
+						}
+					} else if epoch == "" && metadata.CommitTime != "" {
+						commitTime, err := time.Parse(time.RFC3339, metadata.CommitTime)
+						if err != nil {
+							return packit.BuildResult{}, fmt.Errorf("failed to parse commit time %q: %w", metadata.CommitTime, err)
+						}
+						epoch = strconv.FormatInt(commitTime.Unix(), 10)
+					}
+
+					// SOURCE_DATE_EPOCH keeps its well-known name and is only
+					// meaningful to the builds of the other buildpacks
+					if epoch != "" {
+						layer.BuildEnv.Default("SOURCE_DATE_EPOCH", epoch)
+					}
+				}
+
+				labels = map[string]string{
+					"org.opencontainers.image.revision": metadata.Revision,
+				}
+
+				if metadata.CI != nil {
+					for name, value := range metadata.CI.Variables() {
+						exportVariable(&layer, config, name, value)
+					}
+
+					for name, value := range metadata.CI.Labels() {
+						labels[name] = value
+					}
+				}
+
+				if metadata.Tree != nil {
+					for name, value := range metadata.Tree.Variables() {
+						exportVariable(&layer, config, name, value)
+					}
+
+					labels["io.paketo.git.tree"] = metadata.Tree.Tree
+				}
+
+				if len(metadata.Sources) > 0 {
+					content, err := json.Marshal(metadata.Sources)
+					if err != nil {
+						return packit.BuildResult{}, err
+					}
+
+					labels["io.paketo.git.sources"] = string(content)
+				}
+
+				if config.SignatureMode != SignatureModeOff {
+					if !exist {
+						// There is no history to verify the revision against
+						if config.SignatureRequired {
+							return packit.BuildResult{}, fmt.Errorf("failed to verify signatures: the revision was read from %s and not from a git repository", metadata.Source)
+						}
+
+						logger.Process("Skipping signature verification: no git repository")
+						logger.Break()
+					} else {
+						err = verifySignatures(logger, config, signatureVerifier, &layer, labels, repository.Root, context.Platform.Path)
+						if err != nil {
+							return packit.BuildResult{}, err
+						}
+					}
+				}
+
+				layer.Metadata = key.LayerMetadata(labels)
+			}
+
+			// The flags are not persisted in the layer metadata and are
+			// cleared by a reset, so they are set on both paths
+			layer.Launch = config.LaunchScoped()
+			layer.Build = config.BuildScoped() || config.SourceDateEpoch
+			layer.Cache = true
+
+			if layer.Launch {
+				layer.ExecD = []string{filepath.Join(context.CNBPath, "bin", "git-info")}
+			}
+
+			if len(context.BuildpackInfo.SBOMFormats) > 0 {
+				logger.GeneratingSBOM(root)
+				logger.Break()
+				logger.FormattingSBOM(context.BuildpackInfo.SBOMFormats...)
+
+				layer.SBOM, err = GenerateSourceSBOM(metadata, root, context.BuildpackInfo.SBOMFormats)
+				if err != nil {
+					return packit.BuildResult{}, err
+				}
+			}
 
 			logger.EnvironmentVariables(layer)
 
 			buildResult = packit.BuildResult{
 				Layers: []packit.Layer{layer},
 				Launch: packit.LaunchMetadata{
-					Labels: map[string]string{
-						"org.opencontainers.image.revision": revision,
-					},
+					Labels: labels,
 				},
 			}
 		}
 
-		err = credentialManager.Setup(context.WorkingDir, context.Platform.Path)
-		if err != nil {
-			return packit.BuildResult{}, fmt.Errorf("failed to configure given credentials: %w", err)
+		if len(supplementary) > 0 {
+			buildResult.Layers = append(buildResult.Layers, sourcesLayer)
+		}
+
+		if len(config.Mirrors) > 0 {
+			buildResult.Layers = append(buildResult.Layers, mirrorsLayer)
+		}
+
+		if !credentialsConfigured {
+			err = credentialManager.Setup(context.WorkingDir, context.Platform.Path, config)
+			if err != nil {
+				return packit.BuildResult{}, fmt.Errorf("failed to configure given credentials: %w", err)
+			}
+		}
+
+		if exist && (config.RemoveGitDirectory || config.SanitizeGitDirectory) {
+			err = cleanupGitDirectory(executable, logger, config, repository, context.WorkingDir)
+			if err != nil {
+				return packit.BuildResult{}, err
+			}
 		}
 
 		return buildResult, nil
 	}
 }
+
+// refreshCIRun replaces the pipeline URL and build number of the CI context,
+// which are not part of the layer key, in a reused layer and its labels.
+func refreshCIRun(layer *packit.Layer, config Configuration, key LayerKey, labels map[string]string, metadata Metadata) error {
+	_, err := WriteMetadata(layer.Path, metadata)
+	if err != nil {
+		return err
+	}
+
+	variables := metadata.CI.Variables()
+	for _, name := range []string{"GIT_PIPELINE_URL", "GIT_BUILD_NUMBER"} {
+		err = unexportVariable(layer, config, name)
+		if err != nil {
+			return err
+		}
+
+		if value, ok := variables[name]; ok {
+			exportVariable(layer, config, name, value)
+		}
+	}
+
+	ciLabels := metadata.CI.Labels()
+	for _, name := range []string{"io.paketo.git.ci.pipeline-url", "io.paketo.git.ci.build-number"} {
+		delete(labels, name)
+		if value, ok := ciLabels[name]; ok {
+			labels[name] = value
+		}
+	}
+
+	layer.Metadata = key.LayerMetadata(labels)
+
+	return nil
+}
+
+// unexportVariable removes the named environment variable from the layer
+// environment matching the configured scope, including the file that a
+// previous build wrote for it.
+func unexportVariable(layer *packit.Layer, config Configuration, name string) error {
+	name = config.EnvironmentName(name) + ".default"
+
+	env, dir := layer.SharedEnv, "env"
+	switch config.EnvironmentScope {
+	case EnvironmentScopeBuild:
+		env, dir = layer.BuildEnv, "env.build"
+	case EnvironmentScopeLaunch:
+		env, dir = layer.LaunchEnv, "env.launch"
+	}
+
+	delete(env, name)
+
+	err := os.Remove(filepath.Join(layer.Path, dir, name))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %w", name, err)
+	}
+
+	return nil
+}
+
+// exportVariable sets the default value of the named environment variable in
+// the layer environment matching the configured scope, using the configured
+// name for the variable.
+func exportVariable(layer *packit.Layer, config Configuration, name, value string) {
+	name = config.EnvironmentName(name)
+
+	switch config.EnvironmentScope {
+	case EnvironmentScopeBuild:
+		layer.BuildEnv.Default(name, value)
+	case EnvironmentScopeLaunch:
+		layer.LaunchEnv.Default(name, value)
+	default:
+		layer.SharedEnv.Default(name, value)
+	}
+}
+
+// cloneSource clones the source repository into the working directory.
+func cloneSource(executable Executable, logger scribe.Emitter, workingDir string, source Source) error {
+	logger.Process("Cloning source repository")
+	logger.Subprocess("URL: %s", RedactCredentials(source.URL))
+	if source.Ref != "" {
+		logger.Subprocess("Ref: %s", source.Ref)
+	}
+	if source.Commit != "" {
+		logger.Subprocess("Commit: %s", source.Commit)
+	}
+	if source.Depth > 0 {
+		logger.Subprocess("Depth: %d", source.Depth)
+	}
+
+	commit, err := CloneSource(executable, logger, workingDir, source)
+	if err != nil {
+		return err
+	}
+
+	logger.Subprocess("Checked out %s", commit)
+	logger.Break()
+
+	return nil
+}
+
+// verifySignatures verifies the signatures of HEAD, and of the tag pointing at
+// it when requested, recording the outcome in the layer environment and the
+// image labels.
+func verifySignatures(logger scribe.Emitter, config Configuration, signatureVerifier SignatureVerifier, layer *packit.Layer, labels map[string]string, root, platformPath string) error {
+	logger.Process("Verifying signatures (%s)", config.SignatureMode)
+
+	report, err := signatureVerifier.Verify(root, platformPath, config.SignatureMode, config.VerifyTagSignature)
+	if err != nil {
+		return err
+	}
+
+	logSignature(logger, "HEAD", report.Commit)
+	exportVariable(layer, config, "GIT_SIGNATURE_STATUS", report.Commit.Status)
+	labels["io.paketo.git.signature.status"] = report.Commit.Status
+	if report.Commit.Signer != "" {
+		labels["io.paketo.git.signature.signer"] = report.Commit.Signer
+	}
+
+	failed := report.Commit.Status != SignatureStatusGood
+	if config.VerifyTagSignature {
+		object := "tag"
+		if report.Tag.Ref != "" {
+			object = fmt.Sprintf("tag %s", report.Tag.Ref)
+		}
+
+		logSignature(logger, object, report.Tag)
+		labels["io.paketo.git.signature.tag.status"] = report.Tag.Status
+		if report.Tag.Status == SignatureStatusGood {
+			labels["io.paketo.git.signature.tag"] = report.Tag.Ref
+			labels["io.paketo.git.signature.tag.signer"] = report.Tag.Signer
+		}
+
+		failed = failed || report.Tag.Status != SignatureStatusGood
+	}
+	logger.Break()
+
+	if failed && config.SignatureRequired {
+		message := fmt.Sprintf("commit signature is %s", report.Commit.Status)
+		if config.VerifyTagSignature {
+			message = fmt.Sprintf("%s, tag signature is %s", message, report.Tag.Status)
+		}
+
+		return fmt.Errorf("failed to verify signatures: %s", message)
+	}
+
+	return nil
+}
+
+func logCIContext(logger scribe.Emitter, ci CIContext) {
+	logger.Process("Found CI context (%s)", ci.Provider)
+	if ci.PullRequest != "" {
+		logger.Subprocess("Pull request: %s", ci.PullRequest)
+	}
+	if ci.SourceBranch != "" {
+		logger.Subprocess("Source branch: %s", ci.SourceBranch)
+	}
+	if ci.TargetBranch != "" {
+		logger.Subprocess("Target branch: %s", ci.TargetBranch)
+	}
+	if ci.PipelineURL != "" {
+		logger.Subprocess("Pipeline: %s", ci.PipelineURL)
+	}
+	if ci.BuildNumber != "" {
+		logger.Subprocess("Build number: %s", ci.BuildNumber)
+	}
+	logger.Break()
+}
+
+func logSignature(logger scribe.Emitter, object string, signature Signature) {
+	switch {
+	case signature.Status == SignatureStatusGood:
+		logger.Subprocess("%s: good signature from %s (%s)", object, signature.Signer, signature.Key)
+	case signature.Key != "":
+		logger.Subprocess("%s: %s signature (%s)", object, signature.Status, signature.Key)
+	default:
+		logger.Subprocess("%s: %s", object, signature.Status)
+	}
+}
+
+// cleanWorkspace removes the files selected by BP_GIT_CLEAN from the
+// application source, or lists them for a dry run.
+func cleanWorkspace(executable Executable, logger scribe.Emitter, config Configuration, workingDir string) error {
+	logger.Process("Cleaning workspace (%s)", config.Clean)
+	paths, err := CleanWorkspace(executable, logger, workingDir, config.Clean, config.CleanDryRun)
+	if err != nil {
+		return err
+	}
+
+	if config.CleanDryRun {
+		for _, path := range paths {
+			logger.Subprocess("Would remove %s", path)
+		}
+		logger.Subprocess("Dry run: %d path(s) would be removed", len(paths))
+	} else {
+		logger.Subprocess("Removed %d path(s)", len(paths))
+	}
+	logger.Break()
+
+	return nil
+}
+
+// cleanupGitDirectory removes or sanitizes the .git directory once the
+// metadata has been captured. Repositories rooted above the working directory
+// are left alone as they are not part of the application image.
+func cleanupGitDirectory(executable Executable, logger scribe.Emitter, config Configuration, repository Repository, workingDir string) error {
+	if repository.Root != filepath.Clean(workingDir) {
+		logger.Process("Skipping .git directory cleanup: repository root %s is outside of the application directory", repository.Root)
+		logger.Break()
+		return nil
+	}
+
+	if config.RemoveGitDirectory {
+		logger.Process("Removing .git directory")
+		size, err := RemoveGitDirectory(repository.Root)
+		if err != nil {
+			return err
+		}
+
+		logger.Subprocess("Removed %d bytes", size)
+		logger.Break()
+		return nil
+	}
+
+	logger.Process("Sanitizing .git directory")
+	size, err := SanitizeGitDirectory(executable, logger, repository.Root)
+	if err != nil {
+		return err
+	}
+
+	logger.Subprocess("Removed remotes and hooks (%d bytes)", size)
+	logger.Break()
+	return nil
+}
+
+// checkEmbeddedCredentials warns about, or fails on, URLs with embedded
+// credentials in the .git directory according to the secret policy.
+func checkEmbeddedCredentials(logger scribe.Emitter, config Configuration, repository Repository) error {
+	gitDirs, err := repository.GitDirs()
+	if err != nil {
+		return fmt.Errorf("failed to scan for credentials: %w", err)
+	}
+
+	findings, err := ScanForCredentials(gitDirs...)
+	if err != nil {
+		return err
+	}
+
+	if len(findings) == 0 {
+		return nil
+	}
+
+	logger.Process("Found credentials embedded in git files")
+	for _, finding := range findings {
+		logger.Subprocess("%s:%d: %s", finding.File, finding.Line, finding.URL)
+	}
+
+	if config.SecretPolicy == SecretPolicyFail {
+		logger.Break()
+		return fmt.Errorf("found %d credential(s) embedded in git files: remove them from the repository configuration or set BP_GIT_SECRET_POLICY", len(findings))
+	}
+
+	logger.Subprocess("Warning: these credentials will be part of the application image unless BP_GIT_REMOVE_DIR is set")
+	logger.Break()
+
+	return nil
+}
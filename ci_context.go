@@ -0,0 +1,217 @@
+package git
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// The CI systems that the build context can be read from.
+const (
+	CIProviderGitHubActions = "github-actions"
+	CIProviderGitLabCI      = "gitlab-ci"
+	CIProviderJenkins       = "jenkins"
+	CIProviderTekton        = "tekton"
+	CIProviderBuildkite     = "buildkite"
+	CIProviderCircleCI      = "circleci"
+)
+
+// CIContext describes the CI run that the application was built by. It is
+// read from the variables that the CI system sets and that are passed to the
+// build.
+type CIContext struct {
+	Provider     string `toml:"provider" json:"provider"`
+	PullRequest  string `toml:"pull_request,omitempty" json:"pull_request,omitempty"`
+	SourceBranch string `toml:"source_branch,omitempty" json:"source_branch,omitempty"`
+	TargetBranch string `toml:"target_branch,omitempty" json:"target_branch,omitempty"`
+	PipelineURL  string `toml:"pipeline_url,omitempty" json:"pipeline_url,omitempty"`
+	BuildNumber  string `toml:"build_number,omitempty" json:"build_number,omitempty"`
+}
+
+// ciDetector reads the CI context from the variables of a single CI system.
+type ciDetector struct {
+	provider string
+	detect   func(Environment) bool
+	context  func(Environment) CIContext
+}
+
+var ciDetectors = []ciDetector{
+	{
+		provider: CIProviderGitHubActions,
+		detect:   flag("GITHUB_ACTIONS"),
+		context: func(environment Environment) CIContext {
+			context := CIContext{
+				SourceBranch: environment["GITHUB_HEAD_REF"],
+				TargetBranch: environment["GITHUB_BASE_REF"],
+				BuildNumber:  environment["GITHUB_RUN_NUMBER"],
+			}
+
+			// Pull requests check out refs/pull/<number>/merge
+			if ref, ok := strings.CutPrefix(environment["GITHUB_REF"], "refs/pull/"); ok {
+				context.PullRequest, _, _ = strings.Cut(ref, "/")
+			}
+
+			if branch, ok := strings.CutPrefix(environment["GITHUB_REF"], "refs/heads/"); ok && context.SourceBranch == "" {
+				context.SourceBranch = branch
+			}
+
+			if environment["GITHUB_SERVER_URL"] != "" && environment["GITHUB_REPOSITORY"] != "" && environment["GITHUB_RUN_ID"] != "" {
+				context.PipelineURL = fmt.Sprintf("%s/%s/actions/runs/%s", environment["GITHUB_SERVER_URL"], environment["GITHUB_REPOSITORY"], environment["GITHUB_RUN_ID"])
+			}
+
+			return context
+		},
+	},
+	{
+		provider: CIProviderGitLabCI,
+		detect:   flag("GITLAB_CI"),
+		context: func(environment Environment) CIContext {
+			return CIContext{
+				PullRequest:  environment["CI_MERGE_REQUEST_IID"],
+				SourceBranch: firstOf(environment, "CI_MERGE_REQUEST_SOURCE_BRANCH_NAME", "CI_COMMIT_BRANCH"),
+				TargetBranch: environment["CI_MERGE_REQUEST_TARGET_BRANCH_NAME"],
+				PipelineURL:  environment["CI_PIPELINE_URL"],
+				BuildNumber:  environment["CI_PIPELINE_IID"],
+			}
+		},
+	},
+	{
+		provider: CIProviderJenkins,
+		detect: func(environment Environment) bool {
+			return environment["JENKINS_URL"] != ""
+		},
+		context: func(environment Environment) CIContext {
+			return CIContext{
+				PullRequest:  environment["CHANGE_ID"],
+				SourceBranch: strings.TrimPrefix(firstOf(environment, "CHANGE_BRANCH", "BRANCH_NAME", "GIT_BRANCH"), "origin/"),
+				TargetBranch: environment["CHANGE_TARGET"],
+				PipelineURL:  environment["BUILD_URL"],
+				BuildNumber:  environment["BUILD_NUMBER"],
+			}
+		},
+	},
+	{
+		// Tekton does not set any variables of its own, so these are the
+		// names that a pipeline is expected to map its parameters to
+		provider: CIProviderTekton,
+		detect: func(environment Environment) bool {
+			return environment["TEKTON_PIPELINE_RUN"] != ""
+		},
+		context: func(environment Environment) CIContext {
+			return CIContext{
+				PullRequest:  environment["TEKTON_PULL_REQUEST_NUMBER"],
+				SourceBranch: environment["TEKTON_SOURCE_BRANCH"],
+				TargetBranch: environment["TEKTON_TARGET_BRANCH"],
+				PipelineURL:  environment["TEKTON_PIPELINE_RUN_URL"],
+				BuildNumber:  environment["TEKTON_PIPELINE_RUN"],
+			}
+		},
+	},
+	{
+		provider: CIProviderBuildkite,
+		detect:   flag("BUILDKITE"),
+		context: func(environment Environment) CIContext {
+			context := CIContext{
+				SourceBranch: environment["BUILDKITE_BRANCH"],
+				TargetBranch: environment["BUILDKITE_PULL_REQUEST_BASE_BRANCH"],
+				PipelineURL:  environment["BUILDKITE_BUILD_URL"],
+				BuildNumber:  environment["BUILDKITE_BUILD_NUMBER"],
+			}
+
+			// BUILDKITE_PULL_REQUEST is "false" outside of pull requests
+			if number := environment["BUILDKITE_PULL_REQUEST"]; number != "false" {
+				context.PullRequest = number
+			}
+
+			return context
+		},
+	},
+	{
+		provider: CIProviderCircleCI,
+		detect:   flag("CIRCLECI"),
+		context: func(environment Environment) CIContext {
+			context := CIContext{
+				PullRequest:  environment["CIRCLE_PR_NUMBER"],
+				SourceBranch: environment["CIRCLE_BRANCH"],
+				PipelineURL:  environment["CIRCLE_BUILD_URL"],
+				BuildNumber:  environment["CIRCLE_BUILD_NUM"],
+			}
+
+			// CIRCLE_PR_NUMBER is only set for pull requests from forks
+			if context.PullRequest == "" && environment["CIRCLE_PULL_REQUEST"] != "" {
+				context.PullRequest = path.Base(environment["CIRCLE_PULL_REQUEST"])
+			}
+
+			return context
+		},
+	},
+}
+
+func flag(name string) func(Environment) bool {
+	return func(environment Environment) bool {
+		return strings.EqualFold(environment[name], "true")
+	}
+}
+
+func firstOf(environment Environment, names ...string) string {
+	for _, name := range names {
+		if value := environment[name]; value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// DetectCIContext returns the context of the first CI system whose variables
+// are present in the environment. The returned boolean reports whether any
+// CI system was detected.
+func DetectCIContext(environment Environment) (CIContext, bool) {
+	for _, detector := range ciDetectors {
+		if detector.detect(environment) {
+			context := detector.context(environment)
+			context.Provider = detector.provider
+			context.PipelineURL = RedactCredentials(context.PipelineURL)
+
+			return context, true
+		}
+	}
+
+	return CIContext{}, false
+}
+
+// Variables returns the environment variables that describe the context,
+// keyed by their default name. Empty values are left out.
+func (c CIContext) Variables() map[string]string {
+	return nonEmpty(map[string]string{
+		"GIT_CI_PROVIDER":   c.Provider,
+		"GIT_PR_NUMBER":     c.PullRequest,
+		"GIT_SOURCE_BRANCH": c.SourceBranch,
+		"GIT_TARGET_BRANCH": c.TargetBranch,
+		"GIT_PIPELINE_URL":  c.PipelineURL,
+		"GIT_BUILD_NUMBER":  c.BuildNumber,
+	})
+}
+
+// Labels returns the image labels that describe the context. Empty values
+// are left out.
+func (c CIContext) Labels() map[string]string {
+	return nonEmpty(map[string]string{
+		"io.paketo.git.ci.provider":      c.Provider,
+		"io.paketo.git.ci.pull-request":  c.PullRequest,
+		"io.paketo.git.ci.source-branch": c.SourceBranch,
+		"io.paketo.git.ci.target-branch": c.TargetBranch,
+		"io.paketo.git.ci.pipeline-url":  c.PipelineURL,
+		"io.paketo.git.ci.build-number":  c.BuildNumber,
+	})
+}
+
+func nonEmpty(values map[string]string) map[string]string {
+	for key, value := range values {
+		if value == "" {
+			delete(values, key)
+		}
+	}
+
+	return values
+}
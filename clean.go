@@ -0,0 +1,91 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// The files that can be removed from the application source.
+const (
+	CleanModeUntracked = "untracked"
+	CleanModeIgnored   = "ignored"
+	CleanModeAll       = "all"
+)
+
+// CleanWorkspace removes the files below dir that are not tracked at HEAD:
+// the untracked files that .gitignore does not exclude (untracked), the
+// files that it excludes (ignored) or both (all). Ignored and untracked
+// directories are removed as a whole, except in untracked mode, where the
+// untracked files are removed one by one so that the ignored files in the
+// same directories are kept, along with the directories that hold them.
+// Nested repositories are always removed as a whole. Files that are staged
+// but not committed count as untracked. It returns the removed paths,
+// relative to dir, or only lists them when dryRun is true.
+func CleanWorkspace(executable Executable, logger scribe.Emitter, dir, mode string, dryRun bool) ([]string, error) {
+	args := []string{"ls-files", "-z", "--others"}
+	switch mode {
+	case CleanModeUntracked:
+		args = append(args, "--exclude-standard")
+	case CleanModeIgnored:
+		args = append(args, "--directory", "--ignored", "--exclude-standard")
+	case CleanModeAll:
+		args = append(args, "--directory")
+	default:
+		return nil, fmt.Errorf("failed to clean workspace: unknown mode %q", mode)
+	}
+
+	output, err := runGitRaw(executable, logger, dir, args...)
+	if err != nil {
+		return nil, err
+	}
+	paths := splitPaths(output)
+
+	if mode != CleanModeIgnored {
+		output, err = runGitRaw(executable, logger, dir, "diff", "-z", "--cached", "--name-only", "--relative", "--diff-filter=A", "HEAD")
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, splitPaths(output)...)
+	}
+
+	sort.Strings(paths)
+
+	if dryRun {
+		return paths, nil
+	}
+
+	for _, path := range paths {
+		err = os.RemoveAll(filepath.Join(dir, path))
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove %s: %w", path, err)
+		}
+
+		// Like git clean -d, the directories that are left empty are
+		// removed as well
+		for parent := filepath.Dir(path); parent != "."; parent = filepath.Dir(parent) {
+			if os.Remove(filepath.Join(dir, parent)) != nil {
+				break
+			}
+		}
+	}
+
+	return paths, nil
+}
+
+// splitPaths returns the paths of NUL separated git output without the
+// trailing slash that marks directories.
+func splitPaths(output string) []string {
+	var paths []string
+	for _, path := range strings.Split(output, "\x00") {
+		if path = strings.TrimSuffix(path, "/"); path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths
+}
@@ -0,0 +1,186 @@
+package git_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/git/fakes"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testClean(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		executable *fakes.Executable
+		executions []pexec.Execution
+		outputs    map[string]string
+		logger     scribe.Emitter
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(workingDir, "build", "out"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "build", "out", "app"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, ".env"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "notes.txt"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "staged.txt"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "tracked.txt"), nil, 0644)).To(Succeed())
+
+		outputs = map[string]string{
+			"ls-files -z --others --exclude-standard":                       "notes.txt\x00",
+			"ls-files -z --others --directory --ignored --exclude-standard": ".env\x00build/\x00",
+			"ls-files -z --others --directory":                              ".env\x00build/\x00notes.txt\x00",
+			"diff -z --cached --name-only --relative --diff-filter=A HEAD":  "staged.txt\x00",
+		}
+
+		executions = nil
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			executions = append(executions, execution)
+			fmt.Fprint(execution.Stdout, outputs[strings.Join(execution.Args, " ")])
+			return nil
+		}
+
+		logger = scribe.NewEmitter(bytes.NewBuffer(nil))
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	context("CleanWorkspace", func() {
+		context("when removing the untracked files", func() {
+			it("keeps the ignored files", func() {
+				paths, err := git.CleanWorkspace(executable, logger, workingDir, "untracked", false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(paths).To(Equal([]string{"notes.txt", "staged.txt"}))
+
+				Expect(executions).To(HaveLen(2))
+				Expect(executions[0].Dir).To(Equal(workingDir))
+				Expect(executions[1].Dir).To(Equal(workingDir))
+
+				Expect(filepath.Join(workingDir, "notes.txt")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(workingDir, "staged.txt")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(workingDir, ".env")).To(BeAnExistingFile())
+				Expect(filepath.Join(workingDir, "build")).To(BeADirectory())
+				Expect(filepath.Join(workingDir, "tracked.txt")).To(BeAnExistingFile())
+			})
+		})
+
+		context("when an untracked directory contains ignored files", func() {
+			it.Before(func() {
+				Expect(os.MkdirAll(filepath.Join(workingDir, "tmp", "scratch"), os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tmp", "scratch", "draft.txt"), nil, 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tmp", "notes.txt"), nil, 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "tmp", "debug.log"), nil, 0644)).To(Succeed())
+
+				outputs["ls-files -z --others --exclude-standard"] = "tmp/notes.txt\x00tmp/scratch/draft.txt\x00"
+			})
+
+			it("removes the untracked files and keeps the ignored ones", func() {
+				paths, err := git.CleanWorkspace(executable, logger, workingDir, "untracked", false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(paths).To(Equal([]string{"staged.txt", "tmp/notes.txt", "tmp/scratch/draft.txt"}))
+
+				Expect(filepath.Join(workingDir, "tmp", "debug.log")).To(BeAnExistingFile())
+				Expect(filepath.Join(workingDir, "tmp", "notes.txt")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(workingDir, "tmp", "scratch")).NotTo(BeAnExistingFile())
+			})
+		})
+
+		context("when removing the ignored files", func() {
+			it("keeps the untracked files", func() {
+				paths, err := git.CleanWorkspace(executable, logger, workingDir, "ignored", false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(paths).To(Equal([]string{".env", "build"}))
+
+				Expect(executions).To(HaveLen(1))
+
+				Expect(filepath.Join(workingDir, ".env")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(workingDir, "build")).NotTo(BeADirectory())
+				Expect(filepath.Join(workingDir, "notes.txt")).To(BeAnExistingFile())
+				Expect(filepath.Join(workingDir, "staged.txt")).To(BeAnExistingFile())
+			})
+		})
+
+		context("when removing all files", func() {
+			it("only keeps the tracked files", func() {
+				paths, err := git.CleanWorkspace(executable, logger, workingDir, "all", false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(paths).To(Equal([]string{".env", "build", "notes.txt", "staged.txt"}))
+
+				entries, err := os.ReadDir(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+				Expect(entries[0].Name()).To(Equal("tracked.txt"))
+			})
+		})
+
+		context("when it is a dry run", func() {
+			it("lists the paths without removing them", func() {
+				paths, err := git.CleanWorkspace(executable, logger, workingDir, "all", true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(paths).To(Equal([]string{".env", "build", "notes.txt", "staged.txt"}))
+
+				entries, err := os.ReadDir(workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(5))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the mode is unknown", func() {
+				it("returns an error", func() {
+					_, err := git.CleanWorkspace(executable, logger, workingDir, "everything", false)
+					Expect(err).To(MatchError(`failed to clean workspace: unknown mode "everything"`))
+				})
+			})
+
+			context("when git fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(pexec.Execution) error {
+						return errors.New("some-error")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := git.CleanWorkspace(executable, logger, workingDir, "ignored", false)
+					Expect(err).To(MatchError("failed to execute 'git ls-files -z --others --directory --ignored --exclude-standard': some-error"))
+				})
+			})
+
+			context("when the staged files cannot be listed", func() {
+				it.Before(func() {
+					stub := executable.ExecuteCall.Stub
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						if execution.Args[0] == "diff" {
+							return errors.New("some-error")
+						}
+						return stub(execution)
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := git.CleanWorkspace(executable, logger, workingDir, "untracked", false)
+					Expect(err).To(MatchError("failed to execute 'git diff -z --cached --name-only --relative --diff-filter=A HEAD': some-error"))
+					Expect(filepath.Join(workingDir, "notes.txt")).To(BeAnExistingFile())
+				})
+			})
+		})
+	})
+}
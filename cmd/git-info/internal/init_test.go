@@ -0,0 +1,14 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+)
+
+func TestUnitGitInfo(t *testing.T) {
+	suite := spec.New("cmd/git-info/internal", spec.Report(report.Terminal{}), spec.Parallel())
+	suite("Run", testRun)
+	suite.Run(t)
+}
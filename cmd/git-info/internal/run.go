@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/git"
+)
+
+// BuildInfo is the document written to the path given by
+// BPL_GIT_BUILD_INFO_PATH.
+type BuildInfo struct {
+	Git git.Metadata `json:"git"`
+}
+
+// Run reads the build-time git metadata and writes the launch environment
+// variables describing it to output as TOML, as described by the exec.d
+// specification. When BPL_GIT_BUILD_INFO_PATH is set, the metadata is also
+// written to that path as JSON.
+func Run(environment git.Environment, metadataPath string, output io.Writer) error {
+	var metadata git.Metadata
+	_, err := toml.DecodeFile(metadataPath, &metadata)
+	if err != nil {
+		return fmt.Errorf("failed to read git metadata: %w", err)
+	}
+
+	if path, ok := environment.Lookup("BPL_GIT_BUILD_INFO_PATH"); ok && path != "" {
+		err = writeBuildInfo(path, metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	enabled := true
+	if value, ok := environment.Lookup("BPL_GIT_INFO_ENV"); ok && value != "" {
+		enabled, err = strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse BPL_GIT_INFO_ENV: %w", err)
+		}
+	}
+
+	if !enabled {
+		return nil
+	}
+
+	prefix := "GIT_"
+	if value, ok := environment.Lookup("BPL_GIT_INFO_ENV_PREFIX"); ok {
+		prefix = value
+	}
+
+	variables := map[string]string{
+		prefix + "REVISION": metadata.Revision,
+	}
+
+	if metadata.Dirty != nil {
+		variables[prefix+"DIRTY"] = strconv.FormatBool(*metadata.Dirty)
+	}
+
+	if metadata.Branch != "" {
+		variables[prefix+"BRANCH"] = metadata.Branch
+	}
+
+	if len(metadata.Tags) > 0 {
+		variables[prefix+"TAGS"] = strings.Join(metadata.Tags, ",")
+	}
+
+	if metadata.Remote != "" {
+		variables[prefix+"REMOTE"] = metadata.Remote
+	}
+
+	if metadata.CommitTime != "" {
+		variables[prefix+"COMMIT_TIME"] = metadata.CommitTime
+	}
+
+	err = toml.NewEncoder(output).Encode(variables)
+	if err != nil {
+		return fmt.Errorf("failed to write environment variables: %w", err)
+	}
+
+	return nil
+}
+
+func writeBuildInfo(path string, metadata git.Metadata) error {
+	content, err := json.MarshalIndent(BuildInfo{Git: metadata}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write build info: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to write build info: %w", err)
+	}
+
+	err = os.WriteFile(path, append(content, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write build info: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/git/cmd/git-info/internal"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRun(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		layerDir     string
+		metadataPath string
+		output       *bytes.Buffer
+	)
+
+	it.Before(func() {
+		var err error
+		layerDir, err = os.MkdirTemp("", "layer")
+		Expect(err).NotTo(HaveOccurred())
+
+		dirty := false
+		metadataPath, err = git.WriteMetadata(layerDir, git.Metadata{
+			Revision:   "sha123456789",
+			Branch:     "main",
+			Tags:       []string{"v1.2.3", "latest"},
+			Remote:     "https://example.com/some-repo.git",
+			CommitTime: "2023-01-02T03:04:05+00:00",
+			Dirty:      &dirty,
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		output = bytes.NewBuffer(nil)
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(layerDir)).To(Succeed())
+	})
+
+	it("writes the git environment variables", func() {
+		err := internal.Run(git.Environment{}, metadataPath, output)
+		Expect(err).NotTo(HaveOccurred())
+
+		var variables map[string]string
+		_, err = toml.Decode(output.String(), &variables)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(variables).To(Equal(map[string]string{
+			"GIT_REVISION":    "sha123456789",
+			"GIT_BRANCH":      "main",
+			"GIT_TAGS":        "v1.2.3,latest",
+			"GIT_REMOTE":      "https://example.com/some-repo.git",
+			"GIT_DIRTY":       "false",
+			"GIT_COMMIT_TIME": "2023-01-02T03:04:05+00:00",
+		}))
+	})
+
+	context("when the dirty flag was not checked", func() {
+		it.Before(func() {
+			var err error
+			metadataPath, err = git.WriteMetadata(layerDir, git.Metadata{Revision: "sha123456789"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("does not report the tree as clean", func() {
+			err := internal.Run(git.Environment{}, metadataPath, output)
+			Expect(err).NotTo(HaveOccurred())
+
+			var variables map[string]string
+			_, err = toml.Decode(output.String(), &variables)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(variables).To(Equal(map[string]string{
+				"GIT_REVISION": "sha123456789",
+			}))
+		})
+	})
+
+	context("when BPL_GIT_INFO_ENV_PREFIX is set", func() {
+		it("uses the prefix", func() {
+			err := internal.Run(git.Environment{"BPL_GIT_INFO_ENV_PREFIX": "APP_"}, metadataPath, output)
+			Expect(err).NotTo(HaveOccurred())
+
+			var variables map[string]string
+			_, err = toml.Decode(output.String(), &variables)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(variables).To(HaveKeyWithValue("APP_REVISION", "sha123456789"))
+			Expect(variables).NotTo(HaveKey("GIT_REVISION"))
+		})
+	})
+
+	context("when BPL_GIT_INFO_ENV is false", func() {
+		it("does not write any environment variables", func() {
+			err := internal.Run(git.Environment{"BPL_GIT_INFO_ENV": "false"}, metadataPath, output)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.String()).To(BeEmpty())
+		})
+	})
+
+	context("when BPL_GIT_BUILD_INFO_PATH is set", func() {
+		it("writes the build info file", func() {
+			path := filepath.Join(layerDir, "public", ".well-known", "build-info.json")
+			err := internal.Run(git.Environment{"BPL_GIT_BUILD_INFO_PATH": path}, metadataPath, output)
+			Expect(err).NotTo(HaveOccurred())
+
+			content, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+
+			var buildInfo internal.BuildInfo
+			Expect(json.Unmarshal(content, &buildInfo)).To(Succeed())
+			Expect(buildInfo.Git.Revision).To(Equal("sha123456789"))
+			Expect(buildInfo.Git.Tags).To(Equal([]string{"v1.2.3", "latest"}))
+		})
+	})
+
+	context("failure cases", func() {
+		context("when the metadata file cannot be read", func() {
+			it("returns an error", func() {
+				err := internal.Run(git.Environment{}, filepath.Join(layerDir, "missing.toml"), output)
+				Expect(err).To(MatchError(ContainSubstring("failed to read git metadata")))
+			})
+		})
+
+		context("when BPL_GIT_INFO_ENV is not a boolean", func() {
+			it("returns an error", func() {
+				err := internal.Run(git.Environment{"BPL_GIT_INFO_ENV": "not-a-bool"}, metadataPath, output)
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BPL_GIT_INFO_ENV")))
+			})
+		})
+
+		context("when the build info file cannot be written", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(layerDir, "some-file"), nil, 0644)).To(Succeed())
+			})
+
+			it("returns an error", func() {
+				err := internal.Run(git.Environment{"BPL_GIT_BUILD_INFO_PATH": filepath.Join(layerDir, "some-file", "build-info.json")}, metadataPath, output)
+				Expect(err).To(MatchError(ContainSubstring("failed to write build info")))
+			})
+		})
+	})
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/git/cmd/git-info/internal"
+)
+
+func main() {
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	// The helper lives in <layer>/exec.d, next to the metadata file in <layer>
+	metadataPath := filepath.Join(filepath.Dir(filepath.Dir(executable)), git.MetadataFileTOML)
+
+	err = internal.Run(git.NewEnvironment(os.Environ()), metadataPath, os.NewFile(3, "/dev/fd/3"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
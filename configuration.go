@@ -0,0 +1,408 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The phases that the git environment variables can be made available to.
+const (
+	EnvironmentScopeBuild  = "build"
+	EnvironmentScopeLaunch = "launch"
+	EnvironmentScopeBoth   = "both"
+)
+
+var environmentVariableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Configuration is the set of user-facing options that control the behavior
+// of the buildpack. It is populated from BP_GIT_* environment variables.
+type Configuration struct {
+	// SearchParents enables looking for a .git directory in the parent
+	// directories of the working directory.
+	SearchParents bool
+
+	// CeilingDirectory is the last directory inspected when searching parent
+	// directories for a .git directory.
+	CeilingDirectory string
+
+	// EnvironmentPrefix is prepended to the names of the environment variables
+	// exported by the buildpack.
+	EnvironmentPrefix string
+
+	// EnvironmentNames maps the default names of the exported environment
+	// variables to the names they should be exported as. An explicit name
+	// takes precedence over EnvironmentPrefix.
+	EnvironmentNames map[string]string
+
+	// EnvironmentScope is the phase, or phases, that the exported environment
+	// variables are made available to.
+	EnvironmentScope string
+
+	// SignatureMode selects how the signature of HEAD is verified. It is one
+	// of the SignatureMode* values.
+	SignatureMode string
+
+	// VerifyTagSignature additionally requires a tag pointing at HEAD to have
+	// a valid signature.
+	VerifyTagSignature bool
+
+	// SignatureRequired fails the build when the signature verification does
+	// not succeed.
+	SignatureRequired bool
+
+	// RemoveGitDirectory deletes the .git directory from the application
+	// source at the end of the build.
+	RemoveGitDirectory bool
+
+	// SanitizeGitDirectory removes the remotes, hooks and FETCH_HEAD from the
+	// .git directory at the end of the build.
+	SanitizeGitDirectory bool
+
+	// ArchiveMode removes the export-ignore paths and expands the
+	// export-subst placeholders of the application source, so that it matches
+	// the output of git archive.
+	ArchiveMode bool
+
+	// Clean removes the files that are not tracked at HEAD from the
+	// application source before the metadata is collected. It is one of the
+	// CleanMode* values, or empty when the files are left alone.
+	Clean string
+
+	// CleanDryRun only lists the files that Clean would remove.
+	CleanDryRun bool
+
+	// TreeAttestation records the tree object ID of the application source
+	// as it was built next to the tree of HEAD.
+	TreeAttestation bool
+
+	// SourceDateEpoch exports SOURCE_DATE_EPOCH to the build phase.
+	SourceDateEpoch bool
+
+	// SourceDateEpochOverride is exported as SOURCE_DATE_EPOCH instead of the
+	// committer timestamp of HEAD when set.
+	SourceDateEpochOverride string
+
+	// RestoreMTimes sets the modification time of the tracked files to the
+	// time of their last commit or of HEAD. It is one of the MTimeMode*
+	// values, or empty when the times are left alone.
+	RestoreMTimes string
+
+	// CIContext records the pull request, branches and run of the CI system
+	// that the application is built by.
+	CIContext bool
+
+	// SecretPolicy is applied when credentials are found in the .git
+	// directory. It is one of the SecretPolicy* values.
+	SecretPolicy string
+
+	// CredentialExpiryWarning is how long before their expiry the bound
+	// credentials start being reported as about to expire.
+	CredentialExpiryWarning time.Duration
+
+	// CredentialIntrospection enables asking the GitHub or GitLab API for the
+	// expiry of bound tokens that do not have an expires-at entry.
+	CredentialIntrospection bool
+
+	// CredentialCheck verifies that the bound credentials authenticate
+	// against their context once they are configured.
+	CredentialCheck bool
+
+	// CredentialProvider limits the git-credentials bindings to those of the
+	// given provider.
+	CredentialProvider string
+
+	// EnvironmentCredentials are the credentials given through
+	// BP_GIT_CREDENTIALS_<NAME> environment variables.
+	EnvironmentCredentials []EnvironmentCredential
+
+	// Source is the remote repository that the application source is cloned
+	// from when its URL is given.
+	Source Source
+
+	// Mirrors are the repository URLs that are fetched through a bare mirror
+	// kept in a cache layer.
+	Mirrors []string
+
+	// GitHubAPIURL is the base URL of the GitHub API.
+	GitHubAPIURL string
+
+	// GitLabAPIURL is the base URL of the GitLab API.
+	GitLabAPIURL string
+}
+
+// EnvironmentName returns the name that the environment variable with the
+// given default name should be exported as.
+func (c Configuration) EnvironmentName(name string) string {
+	if mapped, ok := c.EnvironmentNames[name]; ok {
+		return mapped
+	}
+
+	return c.EnvironmentPrefix + name
+}
+
+// BuildScoped reports whether the exported environment variables are made
+// available during the build phase.
+func (c Configuration) BuildScoped() bool {
+	return c.EnvironmentScope == EnvironmentScopeBuild || c.EnvironmentScope == EnvironmentScopeBoth
+}
+
+// LaunchScoped reports whether the exported environment variables are made
+// available during the launch phase.
+func (c Configuration) LaunchScoped() bool {
+	return c.EnvironmentScope == EnvironmentScopeLaunch || c.EnvironmentScope == EnvironmentScopeBoth
+}
+
+// ProvidedEnvironmentCredentials returns the credentials given through
+// environment variables that match the configured credential provider.
+func (c Configuration) ProvidedEnvironmentCredentials() []EnvironmentCredential {
+	var credentials []EnvironmentCredential
+	for _, credential := range c.EnvironmentCredentials {
+		if c.CredentialProvider == "" || credential.Provider == c.CredentialProvider {
+			credentials = append(credentials, credential)
+		}
+	}
+
+	return credentials
+}
+
+// LoadConfiguration reads the buildpack configuration from the given
+// environment.
+func LoadConfiguration(environment Environment) (Configuration, error) {
+	config := Configuration{
+		CeilingDirectory:  "/",
+		EnvironmentScope:  EnvironmentScopeBoth,
+		SignatureMode:     SignatureModeOff,
+		SignatureRequired: true,
+		SecretPolicy:      SecretPolicyWarn,
+		SourceDateEpoch:   true,
+		CIContext:         true,
+		Source:            Source{Depth: 1},
+
+		CredentialExpiryWarning: 7 * 24 * time.Hour,
+		GitHubAPIURL:            credentialProviderDefaults[CredentialProviderGitHub].APIURL,
+		GitLabAPIURL:            credentialProviderDefaults[CredentialProviderGitLab].APIURL,
+	}
+
+	var err error
+	config.SearchParents, err = parseBool(environment, "BP_GIT_SEARCH_PARENTS")
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	if ceiling, ok := environment.Lookup("BP_GIT_CEILING_DIRECTORY"); ok && ceiling != "" {
+		config.CeilingDirectory = ceiling
+	}
+
+	if prefix, ok := environment.Lookup("BP_GIT_ENV_PREFIX"); ok && prefix != "" {
+		if !environmentVariableName.MatchString(prefix) {
+			return Configuration{}, fmt.Errorf("failed to parse BP_GIT_ENV_PREFIX: %q is not a valid environment variable prefix", prefix)
+		}
+		config.EnvironmentPrefix = prefix
+	}
+
+	config.EnvironmentNames, err = parseEnvironmentNames(environment)
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	if scope, ok := environment.Lookup("BP_GIT_ENV_SCOPE"); ok && scope != "" {
+		switch scope {
+		case EnvironmentScopeBuild, EnvironmentScopeLaunch, EnvironmentScopeBoth:
+			config.EnvironmentScope = scope
+		default:
+			return Configuration{}, fmt.Errorf("failed to parse BP_GIT_ENV_SCOPE: %q is not one of %q, %q or %q", scope, EnvironmentScopeBuild, EnvironmentScopeLaunch, EnvironmentScopeBoth)
+		}
+	}
+
+	if mode, ok := environment.Lookup("BP_GIT_VERIFY_SIGNATURE"); ok && mode != "" {
+		switch mode {
+		case SignatureModeGPG, SignatureModeSSH, SignatureModeOff:
+			config.SignatureMode = mode
+		default:
+			return Configuration{}, fmt.Errorf("failed to parse BP_GIT_VERIFY_SIGNATURE: %q is not one of %q, %q or %q", mode, SignatureModeGPG, SignatureModeSSH, SignatureModeOff)
+		}
+	}
+
+	config.VerifyTagSignature, err = parseBool(environment, "BP_GIT_VERIFY_TAG_SIGNATURE")
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	if value, ok := environment.Lookup("BP_GIT_VERIFY_SIGNATURE_REQUIRED"); ok && value != "" {
+		config.SignatureRequired, err = parseBool(environment, "BP_GIT_VERIFY_SIGNATURE_REQUIRED")
+		if err != nil {
+			return Configuration{}, err
+		}
+	}
+
+	config.RemoveGitDirectory, err = parseBool(environment, "BP_GIT_REMOVE_DIR")
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	config.SanitizeGitDirectory, err = parseBool(environment, "BP_GIT_SANITIZE_DIR")
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	config.ArchiveMode, err = parseBool(environment, "BP_GIT_ARCHIVE_MODE")
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	if mode, ok := environment.Lookup("BP_GIT_CLEAN"); ok && mode != "" {
+		switch mode {
+		case CleanModeUntracked, CleanModeIgnored, CleanModeAll:
+			config.Clean = mode
+		default:
+			return Configuration{}, fmt.Errorf("failed to parse BP_GIT_CLEAN: %q is not one of %q, %q or %q", mode, CleanModeUntracked, CleanModeIgnored, CleanModeAll)
+		}
+	}
+
+	config.CleanDryRun, err = parseBool(environment, "BP_GIT_CLEAN_DRY_RUN")
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	config.TreeAttestation, err = parseBool(environment, "BP_GIT_TREE_ATTESTATION")
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	if value, ok := environment.Lookup("BP_GIT_SOURCE_DATE_EPOCH"); ok && value != "" {
+		config.SourceDateEpoch, err = parseBool(environment, "BP_GIT_SOURCE_DATE_EPOCH")
+		if err != nil {
+			return Configuration{}, err
+		}
+	}
+
+	if value, ok := environment.Lookup("BP_GIT_SOURCE_DATE_EPOCH_OVERRIDE"); ok && value != "" {
+		if _, err := strconv.ParseUint(value, 10, 63); err != nil {
+			return Configuration{}, fmt.Errorf("failed to parse BP_GIT_SOURCE_DATE_EPOCH_OVERRIDE: %q is not a number of seconds", value)
+		}
+		config.SourceDateEpochOverride = value
+	}
+
+	if mode, ok := environment.Lookup("BP_GIT_RESTORE_MTIMES"); ok && mode != "" {
+		switch mode {
+		case MTimeModeCommit, MTimeModeHead:
+			config.RestoreMTimes = mode
+		default:
+			return Configuration{}, fmt.Errorf("failed to parse BP_GIT_RESTORE_MTIMES: %q is not one of %q or %q", mode, MTimeModeCommit, MTimeModeHead)
+		}
+	}
+
+	if value, ok := environment.Lookup("BP_GIT_CI_CONTEXT"); ok && value != "" {
+		config.CIContext, err = parseBool(environment, "BP_GIT_CI_CONTEXT")
+		if err != nil {
+			return Configuration{}, err
+		}
+	}
+
+	if policy, ok := environment.Lookup("BP_GIT_SECRET_POLICY"); ok && policy != "" {
+		switch policy {
+		case SecretPolicyWarn, SecretPolicyFail, SecretPolicyIgnore:
+			config.SecretPolicy = policy
+		default:
+			return Configuration{}, fmt.Errorf("failed to parse BP_GIT_SECRET_POLICY: %q is not one of %q, %q or %q", policy, SecretPolicyWarn, SecretPolicyFail, SecretPolicyIgnore)
+		}
+	}
+
+	if value, ok := environment.Lookup("BP_GIT_CREDENTIAL_EXPIRY_WARNING"); ok && value != "" {
+		config.CredentialExpiryWarning, err = time.ParseDuration(value)
+		if err != nil {
+			return Configuration{}, fmt.Errorf("failed to parse BP_GIT_CREDENTIAL_EXPIRY_WARNING: %w", err)
+		}
+	}
+
+	config.CredentialIntrospection, err = parseBool(environment, "BP_GIT_CREDENTIAL_INTROSPECTION")
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	config.CredentialCheck, err = parseBool(environment, "BP_GIT_CREDENTIAL_CHECK")
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	if provider, ok := environment.Lookup("BP_GIT_CREDENTIAL_PROVIDER"); ok {
+		config.CredentialProvider = strings.ToLower(strings.TrimSpace(provider))
+	}
+
+	config.EnvironmentCredentials, err = parseEnvironmentCredentials(environment)
+	if err != nil {
+		return Configuration{}, err
+	}
+
+	config.Source.URL = environment["BP_GIT_SOURCE_URL"]
+	config.Source.Ref = environment["BP_GIT_SOURCE_REF"]
+
+	if value, ok := environment.Lookup("BP_GIT_SOURCE_COMMIT"); ok && value != "" {
+		config.Source.Commit, err = parseCommit(value)
+		if err != nil {
+			return Configuration{}, fmt.Errorf("failed to parse BP_GIT_SOURCE_COMMIT: %w", err)
+		}
+	}
+
+	if value, ok := environment.Lookup("BP_GIT_SOURCE_DEPTH"); ok && value != "" {
+		config.Source.Depth, err = parseDepth(value)
+		if err != nil {
+			return Configuration{}, fmt.Errorf("failed to parse BP_GIT_SOURCE_DEPTH: %w", err)
+		}
+	}
+
+	if mirrors, ok := environment.Lookup("BP_GIT_MIRRORS"); ok && mirrors != "" {
+		config.Mirrors = strings.FieldsFunc(mirrors, func(r rune) bool {
+			return r == ',' || unicode.IsSpace(r)
+		})
+	}
+
+	if apiURL, ok := environment.Lookup("BP_GIT_GITHUB_API_URL"); ok && apiURL != "" {
+		config.GitHubAPIURL = apiURL
+	}
+
+	if apiURL, ok := environment.Lookup("BP_GIT_GITLAB_API_URL"); ok && apiURL != "" {
+		config.GitLabAPIURL = apiURL
+	}
+
+	return config, nil
+}
+
+// parseEnvironmentNames parses BP_GIT_ENV_NAMES, a comma separated list of
+// DEFAULT=NAME pairs.
+func parseEnvironmentNames(environment Environment) (map[string]string, error) {
+	value, ok := environment.Lookup("BP_GIT_ENV_NAMES")
+	if !ok || value == "" {
+		return nil, nil
+	}
+
+	names := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		from, to, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found || !environmentVariableName.MatchString(from) || !environmentVariableName.MatchString(to) {
+			return nil, fmt.Errorf("failed to parse BP_GIT_ENV_NAMES: %q is not of the form DEFAULT=NAME", pair)
+		}
+
+		names[from] = to
+	}
+
+	return names, nil
+}
+
+func parseBool(environment Environment, key string) (bool, error) {
+	value, ok := environment.Lookup(key)
+	if !ok || value == "" {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", key, err)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,322 @@
+package git_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testConfiguration(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("LoadConfiguration", func() {
+		it("returns the defaults", func() {
+			config, err := git.LoadConfiguration(git.Environment{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config).To(Equal(git.Configuration{
+				CeilingDirectory:  "/",
+				EnvironmentScope:  "both",
+				SignatureMode:     "off",
+				SignatureRequired: true,
+				SecretPolicy:      "warn",
+				SourceDateEpoch:   true,
+				CIContext:         true,
+				Source:            git.Source{Depth: 1},
+
+				CredentialExpiryWarning: 168 * time.Hour,
+				GitHubAPIURL:            "https://api.github.com",
+				GitLabAPIURL:            "https://gitlab.com/api/v4",
+			}))
+			Expect(config.BuildScoped()).To(BeTrue())
+			Expect(config.LaunchScoped()).To(BeTrue())
+			Expect(config.EnvironmentName("REVISION")).To(Equal("REVISION"))
+		})
+
+		context("when the parent search is configured", func() {
+			it("returns the configuration", func() {
+				config, err := git.LoadConfiguration(git.Environment{
+					"BP_GIT_SEARCH_PARENTS":    "true",
+					"BP_GIT_CEILING_DIRECTORY": "/some/ceiling",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.SearchParents).To(BeTrue())
+				Expect(config.CeilingDirectory).To(Equal("/some/ceiling"))
+			})
+		})
+
+		context("when the environment variable names are configured", func() {
+			it("returns the configured names", func() {
+				config, err := git.LoadConfiguration(git.Environment{
+					"BP_GIT_ENV_PREFIX": "APP_",
+					"BP_GIT_ENV_NAMES":  "REVISION=SOURCE_REVISION, GIT_METADATA_FILE=METADATA",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.EnvironmentNames).To(Equal(map[string]string{
+					"REVISION":          "SOURCE_REVISION",
+					"GIT_METADATA_FILE": "METADATA",
+				}))
+				Expect(config.EnvironmentName("REVISION")).To(Equal("SOURCE_REVISION"))
+				Expect(config.EnvironmentName("OTHER")).To(Equal("APP_OTHER"))
+			})
+		})
+
+		context("when the environment scope is configured", func() {
+			it("returns the scope", func() {
+				config, err := git.LoadConfiguration(git.Environment{"BP_GIT_ENV_SCOPE": "build"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.BuildScoped()).To(BeTrue())
+				Expect(config.LaunchScoped()).To(BeFalse())
+
+				config, err = git.LoadConfiguration(git.Environment{"BP_GIT_ENV_SCOPE": "launch"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.BuildScoped()).To(BeFalse())
+				Expect(config.LaunchScoped()).To(BeTrue())
+			})
+		})
+
+		context("when signature verification is configured", func() {
+			it("returns the configuration", func() {
+				config, err := git.LoadConfiguration(git.Environment{
+					"BP_GIT_VERIFY_SIGNATURE":          "ssh",
+					"BP_GIT_VERIFY_TAG_SIGNATURE":      "true",
+					"BP_GIT_VERIFY_SIGNATURE_REQUIRED": "false",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.SignatureMode).To(Equal("ssh"))
+				Expect(config.VerifyTagSignature).To(BeTrue())
+				Expect(config.SignatureRequired).To(BeFalse())
+			})
+		})
+
+		context("when the .git directory cleanup is configured", func() {
+			it("returns the configuration", func() {
+				config, err := git.LoadConfiguration(git.Environment{
+					"BP_GIT_REMOVE_DIR":   "true",
+					"BP_GIT_SANITIZE_DIR": "true",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.RemoveGitDirectory).To(BeTrue())
+				Expect(config.SanitizeGitDirectory).To(BeTrue())
+			})
+		})
+
+		context("when the archive mode is enabled", func() {
+			it("returns the configuration", func() {
+				config, err := git.LoadConfiguration(git.Environment{"BP_GIT_ARCHIVE_MODE": "true"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.ArchiveMode).To(BeTrue())
+			})
+		})
+
+		context("when the workspace is cleaned", func() {
+			it("returns the configuration", func() {
+				config, err := git.LoadConfiguration(git.Environment{
+					"BP_GIT_CLEAN":         "ignored",
+					"BP_GIT_CLEAN_DRY_RUN": "true",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Clean).To(Equal("ignored"))
+				Expect(config.CleanDryRun).To(BeTrue())
+			})
+		})
+
+		context("when the tree attestation is enabled", func() {
+			it("returns the configuration", func() {
+				config, err := git.LoadConfiguration(git.Environment{"BP_GIT_TREE_ATTESTATION": "true"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.TreeAttestation).To(BeTrue())
+			})
+		})
+
+		context("when the credential checks are configured", func() {
+			it("returns the configuration", func() {
+				config, err := git.LoadConfiguration(git.Environment{
+					"BP_GIT_CREDENTIAL_EXPIRY_WARNING": "24h",
+					"BP_GIT_CREDENTIAL_INTROSPECTION":  "true",
+					"BP_GIT_CREDENTIAL_CHECK":          "true",
+					"BP_GIT_CREDENTIAL_PROVIDER":       " GitHub",
+					"BP_GIT_GITHUB_API_URL":            "https://github.example.com/api/v3",
+					"BP_GIT_GITLAB_API_URL":            "https://gitlab.example.com/api/v4",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.CredentialExpiryWarning).To(Equal(24 * time.Hour))
+				Expect(config.CredentialIntrospection).To(BeTrue())
+				Expect(config.CredentialCheck).To(BeTrue())
+				Expect(config.CredentialProvider).To(Equal("github"))
+				Expect(config.GitHubAPIURL).To(Equal("https://github.example.com/api/v3"))
+				Expect(config.GitLabAPIURL).To(Equal("https://gitlab.example.com/api/v4"))
+			})
+		})
+
+		context("when SOURCE_DATE_EPOCH is configured", func() {
+			it("returns the override or opt-out", func() {
+				config, err := git.LoadConfiguration(git.Environment{"BP_GIT_SOURCE_DATE_EPOCH_OVERRIDE": "1234567890"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.SourceDateEpoch).To(BeTrue())
+				Expect(config.SourceDateEpochOverride).To(Equal("1234567890"))
+
+				config, err = git.LoadConfiguration(git.Environment{"BP_GIT_SOURCE_DATE_EPOCH": "false"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.SourceDateEpoch).To(BeFalse())
+
+				config, err = git.LoadConfiguration(git.Environment{"BP_GIT_SOURCE_DATE_EPOCH": "1"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.SourceDateEpoch).To(BeTrue())
+				Expect(config.SourceDateEpochOverride).To(BeEmpty())
+
+				config, err = git.LoadConfiguration(git.Environment{"BP_GIT_SOURCE_DATE_EPOCH": "0"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.SourceDateEpoch).To(BeFalse())
+				Expect(config.SourceDateEpochOverride).To(BeEmpty())
+			})
+		})
+
+		context("when the modification times are restored", func() {
+			it("returns the mode", func() {
+				config, err := git.LoadConfiguration(git.Environment{"BP_GIT_RESTORE_MTIMES": "commit"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.RestoreMTimes).To(Equal("commit"))
+			})
+		})
+
+		context("when the CI context is disabled", func() {
+			it("returns the configuration", func() {
+				config, err := git.LoadConfiguration(git.Environment{"BP_GIT_CI_CONTEXT": "false"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.CIContext).To(BeFalse())
+			})
+		})
+
+		context("when mirrors are configured", func() {
+			it("returns the URLs", func() {
+				config, err := git.LoadConfiguration(git.Environment{
+					"BP_GIT_MIRRORS": "https://example.com/a.git, https://example.com/b.git\nhttps://example.com/c.git",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Mirrors).To(Equal([]string{
+					"https://example.com/a.git",
+					"https://example.com/b.git",
+					"https://example.com/c.git",
+				}))
+			})
+		})
+
+		context("when the source repository is configured", func() {
+			it("returns the source", func() {
+				config, err := git.LoadConfiguration(git.Environment{
+					"BP_GIT_SOURCE_URL":    "https://example.com/some-repo.git",
+					"BP_GIT_SOURCE_REF":    "v1.2.3",
+					"BP_GIT_SOURCE_COMMIT": "abcdef0123456789abcdef0123456789abcdef01",
+					"BP_GIT_SOURCE_DEPTH":  "0",
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(config.Source).To(Equal(git.Source{
+					URL:    "https://example.com/some-repo.git",
+					Ref:    "v1.2.3",
+					Commit: "abcdef0123456789abcdef0123456789abcdef01",
+					Depth:  0,
+				}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when BP_GIT_RESTORE_MTIMES is unknown", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_RESTORE_MTIMES": "now"})
+					Expect(err).To(MatchError(`failed to parse BP_GIT_RESTORE_MTIMES: "now" is not one of "commit" or "head"`))
+				})
+			})
+
+			context("when BP_GIT_CLEAN is unknown", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_CLEAN": "everything"})
+					Expect(err).To(MatchError(`failed to parse BP_GIT_CLEAN: "everything" is not one of "untracked", "ignored" or "all"`))
+				})
+			})
+
+			context("when BP_GIT_SOURCE_COMMIT is not a full SHA", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_SOURCE_COMMIT": "a"})
+					Expect(err).To(MatchError(`failed to parse BP_GIT_SOURCE_COMMIT: "a" is not a full commit SHA`))
+
+					_, err = git.LoadConfiguration(git.Environment{"BP_GIT_SOURCE_COMMIT": "z123456789abcdef0123456789abcdef01234567"})
+					Expect(err).To(MatchError(ContainSubstring("is not a full commit SHA")))
+				})
+			})
+
+			context("when BP_GIT_SOURCE_DEPTH is not a number of commits", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_SOURCE_DEPTH": "-1"})
+					Expect(err).To(MatchError(`failed to parse BP_GIT_SOURCE_DEPTH: "-1" is not a number of commits`))
+				})
+			})
+
+			context("when BP_GIT_SOURCE_DATE_EPOCH is not a boolean", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_SOURCE_DATE_EPOCH": "1234567890"})
+					Expect(err).To(MatchError(ContainSubstring("failed to parse BP_GIT_SOURCE_DATE_EPOCH")))
+				})
+			})
+
+			context("when BP_GIT_SOURCE_DATE_EPOCH_OVERRIDE is not a number", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_SOURCE_DATE_EPOCH_OVERRIDE": "yesterday"})
+					Expect(err).To(MatchError(`failed to parse BP_GIT_SOURCE_DATE_EPOCH_OVERRIDE: "yesterday" is not a number of seconds`))
+				})
+			})
+
+			context("when BP_GIT_CREDENTIAL_EXPIRY_WARNING is not a duration", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_CREDENTIAL_EXPIRY_WARNING": "a week"})
+					Expect(err).To(MatchError(ContainSubstring("failed to parse BP_GIT_CREDENTIAL_EXPIRY_WARNING")))
+				})
+			})
+
+			context("when BP_GIT_SECRET_POLICY is unknown", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_SECRET_POLICY": "allow"})
+					Expect(err).To(MatchError(`failed to parse BP_GIT_SECRET_POLICY: "allow" is not one of "warn", "fail" or "ignore"`))
+				})
+			})
+
+			context("when BP_GIT_VERIFY_SIGNATURE is unknown", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_VERIFY_SIGNATURE": "x509"})
+					Expect(err).To(MatchError(`failed to parse BP_GIT_VERIFY_SIGNATURE: "x509" is not one of "gpg", "ssh" or "off"`))
+				})
+			})
+
+			context("when BP_GIT_ENV_PREFIX is not a valid prefix", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_ENV_PREFIX": "1-PREFIX"})
+					Expect(err).To(MatchError(`failed to parse BP_GIT_ENV_PREFIX: "1-PREFIX" is not a valid environment variable prefix`))
+				})
+			})
+
+			context("when BP_GIT_ENV_NAMES is malformed", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_ENV_NAMES": "REVISION"})
+					Expect(err).To(MatchError(`failed to parse BP_GIT_ENV_NAMES: "REVISION" is not of the form DEFAULT=NAME`))
+				})
+			})
+
+			context("when BP_GIT_ENV_SCOPE is unknown", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_ENV_SCOPE": "runtime"})
+					Expect(err).To(MatchError(`failed to parse BP_GIT_ENV_SCOPE: "runtime" is not one of "build", "launch" or "both"`))
+				})
+			})
+
+			context("when BP_GIT_SEARCH_PARENTS is not a boolean", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_SEARCH_PARENTS": "not-a-bool"})
+					Expect(err).To(MatchError(ContainSubstring("failed to parse BP_GIT_SEARCH_PARENTS")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,118 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//go:generate faux --interface TokenIntrospector --output fakes/token_introspector.go
+type TokenIntrospector interface {
+	ExpiresAt(provider, apiURL, token string) (expiresAt time.Time, found bool, err error)
+}
+
+// APITokenIntrospector asks the GitHub or GitLab API when a token expires.
+type APITokenIntrospector struct {
+	client *http.Client
+}
+
+func NewAPITokenIntrospector(client *http.Client) APITokenIntrospector {
+	return APITokenIntrospector{
+		client: client,
+	}
+}
+
+// ExpiresAt returns the expiry of the token. The returned boolean is false
+// when the token does not expire.
+func (i APITokenIntrospector) ExpiresAt(provider, apiURL, token string) (time.Time, bool, error) {
+	switch provider {
+	case CredentialProviderGitHub:
+		response, err := i.get(provider, strings.TrimSuffix(apiURL, "/")+"/user", "Authorization", "Bearer "+token)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		defer response.Body.Close()
+
+		value := response.Header.Get("GitHub-Authentication-Token-Expiration")
+		if value == "" {
+			return time.Time{}, false, nil
+		}
+
+		for _, layout := range []string{"2006-01-02 15:04:05 MST", "2006-01-02 15:04:05 -0700"} {
+			expiresAt, err := time.Parse(layout, value)
+			if err == nil {
+				return expiresAt, true, nil
+			}
+		}
+
+		return time.Time{}, false, fmt.Errorf("failed to introspect %s token: invalid expiration %q", provider, value)
+
+	case CredentialProviderGitLab:
+		response, err := i.get(provider, strings.TrimSuffix(apiURL, "/")+"/personal_access_tokens/self", "PRIVATE-TOKEN", token)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		defer response.Body.Close()
+
+		var self struct {
+			ExpiresAt *string `json:"expires_at"`
+		}
+		err = json.NewDecoder(response.Body).Decode(&self)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to introspect %s token: %w", provider, err)
+		}
+
+		if self.ExpiresAt == nil {
+			return time.Time{}, false, nil
+		}
+
+		expiresAt, err := parseExpiry(*self.ExpiresAt)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("failed to introspect %s token: %w", provider, err)
+		}
+
+		return expiresAt, true, nil
+	}
+
+	return time.Time{}, false, fmt.Errorf("failed to introspect token: unsupported provider %q", provider)
+}
+
+func (i APITokenIntrospector) get(provider, uri, header, value string) (*http.Response, error) {
+	request, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect %s token: %w", provider, err)
+	}
+	request.Header.Set(header, value)
+
+	response, err := i.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to introspect %s token: %w", provider, err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("failed to introspect %s token: unexpected response status %q", provider, response.Status)
+	}
+
+	return response, nil
+}
+
+// parseExpiry parses an RFC 3339 timestamp or a date, which is taken as
+// midnight UTC.
+func parseExpiry(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	expiresAt, err := time.Parse(time.RFC3339, value)
+	if err == nil {
+		return expiresAt, nil
+	}
+
+	expiresAt, err = time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 timestamp nor a date", value)
+	}
+
+	return expiresAt, nil
+}
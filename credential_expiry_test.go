@@ -0,0 +1,139 @@
+package git_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testCredentialExpiry(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		server       *httptest.Server
+		requests     []*http.Request
+		expiration   string
+		introspector git.APITokenIntrospector
+	)
+
+	it.Before(func() {
+		requests = nil
+		expiration = "2023-01-05 03:04:05 UTC"
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			requests = append(requests, req)
+
+			switch req.URL.Path {
+			case "/github/user":
+				if req.Header.Get("Authorization") != "Bearer some-token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				if expiration != "" {
+					w.Header().Set("GitHub-Authentication-Token-Expiration", expiration)
+				}
+				fmt.Fprint(w, `{"login": "some-user"}`)
+
+			case "/gitlab/personal_access_tokens/self":
+				if req.Header.Get("PRIVATE-TOKEN") != "some-token" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				if expiration == "" {
+					fmt.Fprint(w, `{"expires_at": null}`)
+					return
+				}
+				fmt.Fprint(w, `{"expires_at": "2023-01-05"}`)
+
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		introspector = git.NewAPITokenIntrospector(server.Client())
+	})
+
+	it.After(func() {
+		server.Close()
+	})
+
+	context("ExpiresAt", func() {
+		context("when the token is issued by GitHub", func() {
+			it("reads the expiration header", func() {
+				expiresAt, found, err := introspector.ExpiresAt("github", server.URL+"/github/", "some-token")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(expiresAt).To(BeTemporally("==", time.Date(2023, time.January, 5, 3, 4, 5, 0, time.UTC)))
+			})
+
+			context("when the token does not expire", func() {
+				it.Before(func() {
+					expiration = ""
+				})
+
+				it("reports that there is no expiry", func() {
+					_, found, err := introspector.ExpiresAt("github", server.URL+"/github", "some-token")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(found).To(BeFalse())
+				})
+			})
+		})
+
+		context("when the token is issued by GitLab", func() {
+			it("reads the expiry date of the token", func() {
+				expiresAt, found, err := introspector.ExpiresAt("gitlab", server.URL+"/gitlab", "some-token")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(expiresAt).To(Equal(time.Date(2023, time.January, 5, 0, 0, 0, 0, time.UTC)))
+			})
+
+			context("when the token does not expire", func() {
+				it.Before(func() {
+					expiration = ""
+				})
+
+				it("reports that there is no expiry", func() {
+					_, found, err := introspector.ExpiresAt("gitlab", server.URL+"/gitlab", "some-token")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(found).To(BeFalse())
+				})
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the token is rejected", func() {
+				it("returns an error", func() {
+					_, _, err := introspector.ExpiresAt("github", server.URL+"/github", "other-token")
+					Expect(err).To(MatchError(`failed to introspect github token: unexpected response status "401 Unauthorized"`))
+				})
+			})
+
+			context("when the expiration header is malformed", func() {
+				it.Before(func() {
+					expiration = "tomorrow"
+				})
+
+				it("returns an error", func() {
+					_, _, err := introspector.ExpiresAt("github", server.URL+"/github", "some-token")
+					Expect(err).To(MatchError(`failed to introspect github token: invalid expiration "tomorrow"`))
+				})
+			})
+
+			context("when the provider is not supported", func() {
+				it("returns an error", func() {
+					_, _, err := introspector.ExpiresAt("bitbucket", server.URL, "some-token")
+					Expect(err).To(MatchError(`failed to introspect token: unsupported provider "bitbucket"`))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+)
+
+// The providers of bound credentials that the buildpack knows the defaults
+// of.
+const (
+	CredentialProviderGitHub    = "github"
+	CredentialProviderGitLab    = "gitlab"
+	CredentialProviderBitbucket = "bitbucket"
+)
+
+// CredentialProviderDefaults are the context and username applied to a
+// binding of a known provider that does not set them itself, along with the
+// API that serves the default context.
+type CredentialProviderDefaults struct {
+	Context  string
+	Username string
+	APIURL   string
+}
+
+var credentialProviderDefaults = map[string]CredentialProviderDefaults{
+	CredentialProviderGitHub:    {Context: "https://github.com", Username: "x-access-token", APIURL: "https://api.github.com"},
+	CredentialProviderGitLab:    {Context: "https://gitlab.com", Username: "oauth2", APIURL: "https://gitlab.com/api/v4"},
+	CredentialProviderBitbucket: {Context: "https://bitbucket.org", Username: "x-token-auth"},
+}
+
+// credentialProvider returns the provider of the binding, either from its
+// provider entry or from the host of its context.
+func credentialProvider(binding servicebindings.Binding, context string) string {
+	provider := strings.ToLower(binding.Provider)
+	if _, ok := credentialProviderDefaults[provider]; ok {
+		return provider
+	}
+
+	uri, err := url.Parse(context)
+	if err != nil {
+		return ""
+	}
+
+	for provider, defaults := range credentialProviderDefaults {
+		if strings.TrimPrefix(defaults.Context, "https://") == uri.Hostname() {
+			return provider
+		}
+	}
+
+	return ""
+}
+
+// credentialPassword returns the password field of the git credentials held
+// by the binding, or its raw token.
+func credentialPassword(binding servicebindings.Binding) (string, error) {
+	entry, ok := binding.Entries["credentials"]
+	if !ok {
+		token, ok := binding.Entries["token"]
+		if !ok {
+			return "", nil
+		}
+
+		content, err := token.ReadString()
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimSpace(content), nil
+	}
+
+	content, err := entry.ReadString()
+	if err != nil {
+		return "", err
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		if password, found := strings.CutPrefix(strings.TrimSpace(line), "password="); found {
+			return password, nil
+		}
+	}
+
+	return "", nil
+}
+
+// sameHost reports whether both URLs name the same host.
+func sameHost(a, b string) bool {
+	uriA, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+
+	uriB, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(uriA.Hostname(), uriB.Hostname())
+}
@@ -1,28 +1,79 @@
 package git
 
 import (
-	"path/filepath"
-
 	"github.com/paketo-buildpacks/packit/v2"
-	"github.com/paketo-buildpacks/packit/v2/fs"
 )
 
-func Detect(bindingResolver BindingResolver) packit.DetectFunc {
+func Detect(environment Environment, bindingResolver BindingResolver) packit.DetectFunc {
 	return func(context packit.DetectContext) (packit.DetectResult, error) {
-		exist, err := fs.Exists(filepath.Join(context.WorkingDir, ".git"))
+		config, err := LoadConfiguration(environment)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		_, exist, err := FindRepository(context.WorkingDir, config.CeilingDirectory, config.SearchParents)
 		if err != nil {
 			return packit.DetectResult{}, err
 		}
 
-		bindings, err := bindingResolver.Resolve("git-credentials", "", context.Platform.Path)
+		if !exist {
+			// The source is cloned during the build when its URL is given
+			_, exist, err = ResolveSource(config, bindingResolver, context.Platform.Path)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+		}
+
+		if !exist {
+			// The metadata can also be provided without a .git directory
+			_, exist, err = FallbackMetadata(context.WorkingDir, environment)
+			if err != nil {
+				return packit.DetectResult{}, err
+			}
+		}
+
+		// The supplementary sources are cloned during the build even when
+		// nothing else is provided
+		sources, err := bindingResolver.Resolve("git-sources", "", context.Platform.Path)
 		if err != nil {
 			return packit.DetectResult{}, err
 		}
 
-		if !exist && len(bindings) == 0 {
+		bindings, err := bindingResolver.Resolve("git-credentials", config.CredentialProvider, context.Platform.Path)
+		if err != nil {
+			return packit.DetectResult{}, err
+		}
+
+		credentials := len(bindings) > 0 || len(config.ProvidedEnvironmentCredentials()) > 0
+		if !exist && !credentials && len(sources) == 0 {
 			return packit.DetectResult{}, packit.Fail.WithMessage("failed to find .git directory and no git credential service bindings present")
 		}
 
-		return packit.DetectResult{}, nil
+		var provisions []packit.BuildPlanProvision
+		if exist {
+			provisions = append(provisions, packit.BuildPlanProvision{Name: PlanDependencyGitMetadata})
+		}
+
+		if credentials {
+			provisions = append(provisions, packit.BuildPlanProvision{Name: PlanDependencyGitCredentials})
+		}
+
+		// The buildpack participates whether or not other buildpacks require
+		// what it provides, so each provision is also offered on its own
+		// followed by an empty plan.
+		var alternatives []packit.BuildPlan
+		if len(provisions) > 1 {
+			for _, provision := range provisions {
+				alternatives = append(alternatives, packit.BuildPlan{Provides: []packit.BuildPlanProvision{provision}})
+			}
+		}
+		alternatives = append(alternatives, packit.BuildPlan{})
+
+		return packit.DetectResult{
+			Plan: packit.BuildPlan{
+				Provides: provisions,
+				Or:       alternatives,
+			},
+		}, nil
 	}
 }
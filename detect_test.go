@@ -32,7 +32,7 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 
 		bindingResolver = &fakes.BindingResolver{}
 
-		detect = git.Detect(bindingResolver)
+		detect = git.Detect(git.Environment{}, bindingResolver)
 	})
 
 	it.After(func() {
@@ -51,13 +51,134 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 				Platform:   packit.Platform{Path: "some-platform"},
 			})
 			Expect(err).NotTo(HaveOccurred())
-			Expect(result.Plan).To(Equal(packit.BuildPlan{}))
+			Expect(result.Plan).To(Equal(packit.BuildPlan{
+				Provides: []packit.BuildPlanProvision{
+					{Name: "git-metadata"},
+				},
+				Or: []packit.BuildPlan{
+					{},
+				},
+			}))
 
 			Expect(bindingResolver.ResolveCall.Receives.PlatformDir).To(Equal("some-platform"))
 			Expect(bindingResolver.ResolveCall.Receives.Typ).To(Equal("git-credentials"))
 		})
 	})
 
+	context("when a .git directory and git-credentials service bindings are present", func() {
+		it.Before(func() {
+			Expect(os.Mkdir(filepath.Join(workingDir, ".git"), os.ModePerm)).To(Succeed())
+
+			bindingResolver.ResolveCall.Returns.BindingSlice = []servicebindings.Binding{
+				{
+					Path: "some-path",
+				},
+			}
+		})
+
+		it("provides both entries on their own and together", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+				Platform:   packit.Platform{Path: "some-platform"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan).To(Equal(packit.BuildPlan{
+				Provides: []packit.BuildPlanProvision{
+					{Name: "git-metadata"},
+					{Name: "git-credentials"},
+				},
+				Or: []packit.BuildPlan{
+					{Provides: []packit.BuildPlanProvision{{Name: "git-metadata"}}},
+					{Provides: []packit.BuildPlanProvision{{Name: "git-credentials"}}},
+					{},
+				},
+			}))
+		})
+
+		context("when a credential provider is configured", func() {
+			it.Before(func() {
+				detect = git.Detect(git.Environment{"BP_GIT_CREDENTIAL_PROVIDER": "GitLab"}, bindingResolver)
+			})
+
+			it("only resolves the bindings of the provider", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+					Platform:   packit.Platform{Path: "some-platform"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(bindingResolver.ResolveCall.Receives.Provider).To(Equal("gitlab"))
+			})
+		})
+	})
+
+	context("when credentials are given through environment variables", func() {
+		it.Before(func() {
+			detect = git.Detect(git.Environment{"BP_GIT_CREDENTIALS_WORK": "some-token"}, bindingResolver)
+		})
+
+		it("provides git-credentials", func() {
+			result, err := detect(packit.DetectContext{
+				WorkingDir: workingDir,
+				Platform:   packit.Platform{Path: "some-platform"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Plan.Provides).To(Equal([]packit.BuildPlanProvision{{Name: "git-credentials"}}))
+		})
+	})
+
+	context("when a .git directory is present in a parent directory", func() {
+		var appDir string
+
+		it.Before(func() {
+			Expect(os.Mkdir(filepath.Join(workingDir, ".git"), os.ModePerm)).To(Succeed())
+
+			appDir = filepath.Join(workingDir, "some-app")
+			Expect(os.Mkdir(appDir, os.ModePerm)).To(Succeed())
+		})
+
+		context("when searching parent directories is enabled", func() {
+			it.Before(func() {
+				detect = git.Detect(git.Environment{"BP_GIT_SEARCH_PARENTS": "true"}, bindingResolver)
+			})
+
+			it("detects", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: appDir,
+					Platform:   packit.Platform{Path: "some-platform"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+
+		context("when the ceiling directory is below the repository", func() {
+			it.Before(func() {
+				detect = git.Detect(git.Environment{
+					"BP_GIT_SEARCH_PARENTS":    "true",
+					"BP_GIT_CEILING_DIRECTORY": appDir,
+				}, bindingResolver)
+			})
+
+			it("fails detection", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: appDir,
+					Platform:   packit.Platform{Path: "some-platform"},
+				})
+				Expect(err).To(MatchError(packit.Fail.WithMessage("failed to find .git directory and no git credential service bindings present")))
+			})
+		})
+
+		context("when searching parent directories is disabled", func() {
+			it("fails detection", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: appDir,
+					Platform:   packit.Platform{Path: "some-platform"},
+				})
+				Expect(err).To(MatchError(packit.Fail.WithMessage("failed to find .git directory and no git credential service bindings present")))
+			})
+		})
+	})
+
 	context("when a .git directory is not present", func() {
 		context("when there are no git-credentials service bindings", func() {
 			it("fails detections", func() {
@@ -71,10 +192,16 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 
 		context("when there are git-credentials service bindings", func() {
 			it.Before(func() {
-				bindingResolver.ResolveCall.Returns.BindingSlice = []servicebindings.Binding{
-					{
-						Path: "some-path",
-					},
+				bindingResolver.ResolveCall.Stub = func(typ, _, _ string) ([]servicebindings.Binding, error) {
+					if typ != "git-credentials" {
+						return nil, nil
+					}
+
+					return []servicebindings.Binding{
+						{
+							Path: "some-path",
+						},
+					}, nil
 				}
 			})
 			it("detects", func() {
@@ -83,12 +210,83 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 					Platform:   packit.Platform{Path: "some-platform"},
 				})
 				Expect(err).NotTo(HaveOccurred())
-				Expect(result.Plan).To(Equal(packit.BuildPlan{}))
+				Expect(result.Plan).To(Equal(packit.BuildPlan{
+					Provides: []packit.BuildPlanProvision{
+						{Name: "git-credentials"},
+					},
+					Or: []packit.BuildPlan{
+						{},
+					},
+				}))
 
 				Expect(bindingResolver.ResolveCall.Receives.PlatformDir).To(Equal("some-platform"))
 				Expect(bindingResolver.ResolveCall.Receives.Typ).To(Equal("git-credentials"))
 			})
 		})
+
+		context("when there is a git-sources binding", func() {
+			it.Before(func() {
+				bindingResolver.ResolveCall.Stub = func(typ, _, _ string) ([]servicebindings.Binding, error) {
+					if typ != "git-sources" {
+						return nil, nil
+					}
+
+					return []servicebindings.Binding{{Path: "some-path"}}, nil
+				}
+			})
+
+			it("detects without providing anything", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+					Platform:   packit.Platform{Path: "some-platform"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan).To(Equal(packit.BuildPlan{
+					Or: []packit.BuildPlan{
+						{},
+					},
+				}))
+			})
+		})
+
+		context("when the source repository is configured", func() {
+			it.Before(func() {
+				detect = git.Detect(git.Environment{"BP_GIT_SOURCE_URL": "https://example.com/some-repo.git"}, bindingResolver)
+			})
+
+			it("detects", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+					Platform:   packit.Platform{Path: "some-platform"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan.Provides).To(Equal([]packit.BuildPlanProvision{
+					{Name: "git-metadata"},
+				}))
+			})
+		})
+
+		context("when the revision is given through the environment", func() {
+			it.Before(func() {
+				detect = git.Detect(git.Environment{"BP_GIT_REVISION": "sha123456789"}, bindingResolver)
+			})
+
+			it("detects", func() {
+				result, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+					Platform:   packit.Platform{Path: "some-platform"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result.Plan).To(Equal(packit.BuildPlan{
+					Provides: []packit.BuildPlanProvision{
+						{Name: "git-metadata"},
+					},
+					Or: []packit.BuildPlan{
+						{},
+					},
+				}))
+			})
+		})
 	})
 
 	context("failure cases", func() {
@@ -108,6 +306,20 @@ func testDetect(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("when the configuration is invalid", func() {
+			it.Before(func() {
+				detect = git.Detect(git.Environment{"BP_GIT_SEARCH_PARENTS": "not-a-bool"}, bindingResolver)
+			})
+
+			it("returns an error", func() {
+				_, err := detect(packit.DetectContext{
+					WorkingDir: workingDir,
+					Platform:   packit.Platform{Path: "some-platform"},
+				})
+				Expect(err).To(MatchError(ContainSubstring("failed to parse BP_GIT_SEARCH_PARENTS")))
+			})
+		})
+
 		context("when binding resolution fails", func() {
 			it.Before(func() {
 				bindingResolver.ResolveCall.Returns.Error = errors.New("failed to resolve bindings")
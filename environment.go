@@ -0,0 +1,29 @@
+package git
+
+import "strings"
+
+// Environment is the set of build-time environment variables made available
+// to the buildpack.
+type Environment map[string]string
+
+// NewEnvironment creates an Environment from a list of "KEY=value" strings,
+// such as the one returned by os.Environ.
+func NewEnvironment(environ []string) Environment {
+	environment := Environment{}
+	for _, variable := range environ {
+		key, value, found := strings.Cut(variable, "=")
+		if !found {
+			continue
+		}
+
+		environment[key] = value
+	}
+
+	return environment
+}
+
+// Lookup returns the value of the given variable and whether it was set.
+func (e Environment) Lookup(key string) (string, bool) {
+	value, ok := e[key]
+	return value, ok
+}
@@ -0,0 +1,166 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+)
+
+// EnvironmentCredentialPrefix is the prefix of the build-time environment
+// variables that provide git credentials without a service binding.
+const EnvironmentCredentialPrefix = "BP_GIT_CREDENTIALS_"
+
+var environmentCredentialSuffixes = []string{"_FILE", "_CONTEXT", "_USERNAME", "_PROVIDER"}
+
+// EnvironmentCredential is a set of git credentials given through
+// BP_GIT_CREDENTIALS_<NAME> environment variables. It is turned into a
+// virtual git-credentials binding.
+type EnvironmentCredential struct {
+	// Name is the <NAME> part of the variables.
+	Name string
+
+	// Token is the value of BP_GIT_CREDENTIALS_<NAME>.
+	Token string
+
+	// File is the value of BP_GIT_CREDENTIALS_<NAME>_FILE, the path of a file
+	// in the git credential format.
+	File string
+
+	// Context is the value of BP_GIT_CREDENTIALS_<NAME>_CONTEXT.
+	Context string
+
+	// Username is the value of BP_GIT_CREDENTIALS_<NAME>_USERNAME.
+	Username string
+
+	// Provider is the value of BP_GIT_CREDENTIALS_<NAME>_PROVIDER.
+	Provider string
+}
+
+// VariableName returns the name of the environment variable that the
+// credential is given through.
+func (c EnvironmentCredential) VariableName() string {
+	if c.File != "" {
+		return EnvironmentCredentialPrefix + c.Name + "_FILE"
+	}
+
+	return EnvironmentCredentialPrefix + c.Name
+}
+
+// Binding writes the credential into a new directory below dir, with files
+// only readable by the current user, and returns the equivalent binding.
+func (c EnvironmentCredential) Binding(dir string) (servicebindings.Binding, error) {
+	path, err := os.MkdirTemp(dir, "git-credentials")
+	if err != nil {
+		return servicebindings.Binding{}, fmt.Errorf("failed to create binding for %s: %w", c.VariableName(), err)
+	}
+
+	entries := map[string]string{
+		"context":  c.Context,
+		"username": c.Username,
+		"token":    c.Token,
+	}
+
+	if c.File != "" {
+		content, err := os.ReadFile(c.File)
+		if err != nil {
+			return servicebindings.Binding{}, fmt.Errorf("failed to read %s: %w", c.VariableName(), err)
+		}
+
+		entries["credentials"] = string(content)
+	}
+
+	binding := servicebindings.Binding{
+		Name:     c.VariableName(),
+		Path:     path,
+		Type:     "git-credentials",
+		Provider: c.Provider,
+		Entries:  map[string]*servicebindings.Entry{},
+	}
+
+	for name, value := range entries {
+		if value == "" {
+			continue
+		}
+
+		err = os.WriteFile(filepath.Join(path, name), []byte(value), 0600)
+		if err != nil {
+			return servicebindings.Binding{}, fmt.Errorf("failed to create binding for %s: %w", c.VariableName(), err)
+		}
+
+		binding.Entries[name] = servicebindings.NewEntry(filepath.Join(path, name))
+	}
+
+	return binding, nil
+}
+
+// parseEnvironmentCredentials groups the BP_GIT_CREDENTIALS_<NAME>[_SUFFIX]
+// variables by name. Every credential needs either a token or a file.
+func parseEnvironmentCredentials(environment Environment) ([]EnvironmentCredential, error) {
+	credentials := map[string]*EnvironmentCredential{}
+	credential := func(name string) *EnvironmentCredential {
+		if _, ok := credentials[name]; !ok {
+			credentials[name] = &EnvironmentCredential{Name: name}
+		}
+
+		return credentials[name]
+	}
+
+	for key, value := range environment {
+		name, found := strings.CutPrefix(key, EnvironmentCredentialPrefix)
+		if !found || name == "" {
+			continue
+		}
+
+		value = strings.TrimSpace(value)
+
+		var suffix string
+		for _, s := range environmentCredentialSuffixes {
+			if strings.HasSuffix(name, s) && name != s {
+				suffix = s
+				name = strings.TrimSuffix(name, s)
+				break
+			}
+		}
+
+		switch suffix {
+		case "_FILE":
+			credential(name).File = value
+		case "_CONTEXT":
+			credential(name).Context = value
+		case "_USERNAME":
+			credential(name).Username = value
+		case "_PROVIDER":
+			credential(name).Provider = strings.ToLower(value)
+		default:
+			credential(name).Token = value
+		}
+	}
+
+	var result []EnvironmentCredential
+	for _, c := range credentials {
+		// The suffix of a variable cannot be told apart from the end of a
+		// name, so such names are ambiguous
+		for _, suffix := range environmentCredentialSuffixes {
+			if strings.HasSuffix(c.Name, suffix) {
+				return nil, fmt.Errorf("failed to parse %s%s: the name must not end in %s", EnvironmentCredentialPrefix, c.Name, suffix)
+			}
+		}
+
+		switch {
+		case c.Token == "" && c.File == "":
+			return nil, fmt.Errorf("failed to parse %s%s: either %s%s or %s%s_FILE must be set", EnvironmentCredentialPrefix, c.Name, EnvironmentCredentialPrefix, c.Name, EnvironmentCredentialPrefix, c.Name)
+		case c.Token != "" && c.File != "":
+			return nil, fmt.Errorf("failed to parse %s%s: only one of %s%s or %s%s_FILE can be set", EnvironmentCredentialPrefix, c.Name, EnvironmentCredentialPrefix, c.Name, EnvironmentCredentialPrefix, c.Name)
+		}
+
+		result = append(result, *c)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
+	return result, nil
+}
@@ -0,0 +1,137 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testEnvironmentCredentials(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		dir string
+	)
+
+	it.Before(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "credentials")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	context("LoadConfiguration", func() {
+		it("groups the variables by name", func() {
+			config, err := git.LoadConfiguration(git.Environment{
+				"BP_GIT_CREDENTIALS_WORK":          "some-token",
+				"BP_GIT_CREDENTIALS_WORK_PROVIDER": "GitHub",
+				"BP_GIT_CREDENTIALS_WORK_USERNAME": "some-user",
+				"BP_GIT_CREDENTIALS_OTHER_FILE":    "/some/credentials",
+				"BP_GIT_CREDENTIALS_OTHER_CONTEXT": "https://example.com",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.EnvironmentCredentials).To(Equal([]git.EnvironmentCredential{
+				{Name: "OTHER", File: "/some/credentials", Context: "https://example.com"},
+				{Name: "WORK", Token: "some-token", Username: "some-user", Provider: "github"},
+			}))
+
+			config.CredentialProvider = "github"
+			Expect(config.ProvidedEnvironmentCredentials()).To(Equal([]git.EnvironmentCredential{
+				{Name: "WORK", Token: "some-token", Username: "some-user", Provider: "github"},
+			}))
+		})
+
+		context("failure cases", func() {
+			context("when there is neither a token nor a file", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{"BP_GIT_CREDENTIALS_WORK_CONTEXT": "https://example.com"})
+					Expect(err).To(MatchError("failed to parse BP_GIT_CREDENTIALS_WORK: either BP_GIT_CREDENTIALS_WORK or BP_GIT_CREDENTIALS_WORK_FILE must be set"))
+				})
+			})
+
+			context("when there are both a token and a file", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{
+						"BP_GIT_CREDENTIALS_WORK":      "some-token",
+						"BP_GIT_CREDENTIALS_WORK_FILE": "/some/credentials",
+					})
+					Expect(err).To(MatchError("failed to parse BP_GIT_CREDENTIALS_WORK: only one of BP_GIT_CREDENTIALS_WORK or BP_GIT_CREDENTIALS_WORK_FILE can be set"))
+				})
+			})
+
+			context("when the name ends in a suffix", func() {
+				it("returns an error", func() {
+					_, err := git.LoadConfiguration(git.Environment{
+						"BP_GIT_CREDENTIALS_WORK_FILE":         "some-token",
+						"BP_GIT_CREDENTIALS_WORK_FILE_CONTEXT": "https://example.com",
+					})
+					Expect(err).To(MatchError("failed to parse BP_GIT_CREDENTIALS_WORK_FILE: the name must not end in _FILE"))
+				})
+			})
+		})
+	})
+
+	context("Binding", func() {
+		it("writes the token and settings as binding entries", func() {
+			binding, err := git.EnvironmentCredential{
+				Name:     "WORK",
+				Token:    "some-token",
+				Context:  "https://example.com",
+				Provider: "github",
+			}.Binding(dir)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(binding.Name).To(Equal("BP_GIT_CREDENTIALS_WORK"))
+			Expect(binding.Type).To(Equal("git-credentials"))
+			Expect(binding.Provider).To(Equal("github"))
+			Expect(filepath.Dir(binding.Path)).To(Equal(dir))
+			Expect(binding.Entries).To(HaveLen(2))
+
+			token, err := binding.Entries["token"].ReadString()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal("some-token"))
+
+			info, err := os.Stat(filepath.Join(binding.Path, "token"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+			context, err := binding.Entries["context"].ReadString()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(context).To(Equal("https://example.com"))
+		})
+
+		context("when the credentials are given as a file", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(dir, "some-credentials"), []byte("username=some-user\npassword=some-password\n"), 0600)).To(Succeed())
+			})
+
+			it("copies the file as the credentials entry", func() {
+				binding, err := git.EnvironmentCredential{Name: "WORK", File: filepath.Join(dir, "some-credentials")}.Binding(dir)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(binding.Name).To(Equal("BP_GIT_CREDENTIALS_WORK_FILE"))
+
+				content, err := os.ReadFile(filepath.Join(binding.Path, "credentials"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(Equal("username=some-user\npassword=some-password\n"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the file cannot be read", func() {
+				it("returns an error", func() {
+					_, err := git.EnvironmentCredential{Name: "WORK", File: filepath.Join(dir, "missing")}.Binding(dir)
+					Expect(err).To(MatchError(ContainSubstring("failed to read BP_GIT_CREDENTIALS_WORK_FILE")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,37 @@
+package git_test
+
+import (
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testEnvironment(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("NewEnvironment", func() {
+		it("parses the variables", func() {
+			environment := git.NewEnvironment([]string{
+				"SOME_KEY=some-value",
+				"OTHER_KEY=other=value",
+				"EMPTY_KEY=",
+				"MALFORMED",
+			})
+			Expect(environment).To(Equal(git.Environment{
+				"SOME_KEY":  "some-value",
+				"OTHER_KEY": "other=value",
+				"EMPTY_KEY": "",
+			}))
+
+			value, ok := environment.Lookup("EMPTY_KEY")
+			Expect(ok).To(BeTrue())
+			Expect(value).To(Equal(""))
+
+			_, ok = environment.Lookup("MALFORMED")
+			Expect(ok).To(BeFalse())
+		})
+	})
+}
@@ -1,6 +1,10 @@
 package fakes
 
-import "sync"
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/git"
+)
 
 type CredentialManager struct {
 	SetupCall struct {
@@ -9,22 +13,24 @@ type CredentialManager struct {
 		Receives  struct {
 			WorkingDir   string
 			PlatformPath string
+			Config       git.Configuration
 		}
 		Returns struct {
 			Err error
 		}
-		Stub func(string, string) error
+		Stub func(string, string, git.Configuration) error
 	}
 }
 
-func (f *CredentialManager) Setup(param1 string, param2 string) error {
+func (f *CredentialManager) Setup(param1 string, param2 string, param3 git.Configuration) error {
 	f.SetupCall.Lock()
 	defer f.SetupCall.Unlock()
 	f.SetupCall.CallCount++
 	f.SetupCall.Receives.WorkingDir = param1
 	f.SetupCall.Receives.PlatformPath = param2
+	f.SetupCall.Receives.Config = param3
 	if f.SetupCall.Stub != nil {
-		return f.SetupCall.Stub(param1, param2)
+		return f.SetupCall.Stub(param1, param2, param3)
 	}
 	return f.SetupCall.Returns.Err
 }
@@ -0,0 +1,39 @@
+package fakes
+
+import (
+	"sync"
+
+	"github.com/paketo-buildpacks/git"
+)
+
+type SignatureVerifier struct {
+	VerifyCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			WorkingDir   string
+			PlatformPath string
+			Mode         string
+			VerifyTags   bool
+		}
+		Returns struct {
+			Report git.SignatureReport
+			Err    error
+		}
+		Stub func(string, string, string, bool) (git.SignatureReport, error)
+	}
+}
+
+func (f *SignatureVerifier) Verify(param1 string, param2 string, param3 string, param4 bool) (git.SignatureReport, error) {
+	f.VerifyCall.Lock()
+	defer f.VerifyCall.Unlock()
+	f.VerifyCall.CallCount++
+	f.VerifyCall.Receives.WorkingDir = param1
+	f.VerifyCall.Receives.PlatformPath = param2
+	f.VerifyCall.Receives.Mode = param3
+	f.VerifyCall.Receives.VerifyTags = param4
+	if f.VerifyCall.Stub != nil {
+		return f.VerifyCall.Stub(param1, param2, param3, param4)
+	}
+	return f.VerifyCall.Returns.Report, f.VerifyCall.Returns.Err
+}
@@ -0,0 +1,37 @@
+package fakes
+
+import (
+	"sync"
+	"time"
+)
+
+type TokenIntrospector struct {
+	ExpiresAtCall struct {
+		sync.Mutex
+		CallCount int
+		Receives  struct {
+			Provider string
+			ApiURL   string
+			Token    string
+		}
+		Returns struct {
+			ExpiresAt time.Time
+			Found     bool
+			Err       error
+		}
+		Stub func(string, string, string) (time.Time, bool, error)
+	}
+}
+
+func (f *TokenIntrospector) ExpiresAt(param1 string, param2 string, param3 string) (time.Time, bool, error) {
+	f.ExpiresAtCall.Lock()
+	defer f.ExpiresAtCall.Unlock()
+	f.ExpiresAtCall.CallCount++
+	f.ExpiresAtCall.Receives.Provider = param1
+	f.ExpiresAtCall.Receives.ApiURL = param2
+	f.ExpiresAtCall.Receives.Token = param3
+	if f.ExpiresAtCall.Stub != nil {
+		return f.ExpiresAtCall.Stub(param1, param2, param3)
+	}
+	return f.ExpiresAtCall.Returns.ExpiresAt, f.ExpiresAtCall.Returns.Found, f.ExpiresAtCall.Returns.Err
+}
@@ -0,0 +1,42 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// runGit executes git with the given arguments in dir and returns its
+// trimmed standard output. When the command fails, its output is logged as
+// detail and the error names the failed command. Both are redacted as the
+// arguments may contain URLs with credentials.
+func runGit(executable Executable, logger scribe.Emitter, dir string, args ...string) (string, error) {
+	output, err := runGitRaw(executable, logger, dir, args...)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// runGitRaw is runGit without trimming the output, for the NUL separated
+// output of -z commands where whitespace is part of the paths.
+func runGitRaw(executable Executable, logger scribe.Emitter, dir string, args ...string) (string, error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	err := executable.Execute(pexec.Execution{
+		Args:   args,
+		Dir:    dir,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		logger.Detail(RedactCredentials(stdout.String() + stderr.String()))
+		return "", fmt.Errorf("failed to execute 'git %s': %w", RedactCredentials(strings.Join(args, " ")), err)
+	}
+
+	return stdout.String(), nil
+}
@@ -3,9 +3,14 @@ package git
 import (
 	"bytes"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"github.com/paketo-buildpacks/packit/v2/chronos"
 	"github.com/paketo-buildpacks/packit/v2/pexec"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
 	"github.com/paketo-buildpacks/packit/v2/servicebindings"
@@ -16,26 +21,83 @@ type BindingResolver interface {
 	Resolve(typ, provider, platformDir string) ([]servicebindings.Binding, error)
 }
 
+// CredentialMechanismHelper is the mechanism that bound credentials are
+// configured with: a credential helper serving the binding.
+const CredentialMechanismHelper = "helper"
+
 type GitCredentialManager struct {
 	bindingResolver BindingResolver
 	executable      Executable
+	introspector    TokenIntrospector
+	clock           chronos.Clock
 	logs            scribe.Emitter
+	client          *http.Client
+
+	environmentCredentialsDir string
 }
 
-func NewGitCredentialManager(bindingResolver BindingResolver, executable Executable, logs scribe.Emitter) GitCredentialManager {
+func NewGitCredentialManager(bindingResolver BindingResolver, executable Executable, introspector TokenIntrospector, clock chronos.Clock, logs scribe.Emitter) GitCredentialManager {
 	return GitCredentialManager{
 		bindingResolver: bindingResolver,
 		executable:      executable,
+		introspector:    introspector,
+		clock:           clock,
 		logs:            logs,
+		client:          &http.Client{Timeout: 30 * time.Second},
+
+		environmentCredentialsDir: filepath.Join(os.TempDir(), "git-credentials-environment"),
+	}
+}
+
+// WithEnvironmentCredentialsDir returns a copy of the manager that writes the
+// bindings of the credentials given through environment variables into dir.
+func (g GitCredentialManager) WithEnvironmentCredentialsDir(dir string) GitCredentialManager {
+	g.environmentCredentialsDir = dir
+	return g
+}
+
+func (g GitCredentialManager) Setup(workingDir, platformDir string, config Configuration) error {
+	// The credentials given through environment variables are written
+	// outside of the layers and the application directory so that they never
+	// end up in the image. They are served to git for the rest of the build,
+	// so they are only removed by the next setup or when this one fails.
+	err := os.RemoveAll(g.environmentCredentialsDir)
+	if err != nil {
+		return fmt.Errorf("failed to remove environment credentials: %w", err)
 	}
+
+	err = g.setup(workingDir, platformDir, config)
+	if err != nil {
+		_ = os.RemoveAll(g.environmentCredentialsDir)
+		return err
+	}
+
+	return nil
 }
 
-func (g GitCredentialManager) Setup(workingDir, platformDir string) error {
-	bindings, err := g.bindingResolver.Resolve("git-credentials", "", platformDir)
+func (g GitCredentialManager) setup(workingDir, platformDir string, config Configuration) error {
+	bindings, err := g.bindingResolver.Resolve("git-credentials", config.CredentialProvider, platformDir)
 	if err != nil {
 		return err
 	}
 
+	credentials := config.ProvidedEnvironmentCredentials()
+	if len(credentials) > 0 {
+		err = os.MkdirAll(g.environmentCredentialsDir, 0700)
+		if err != nil {
+			return fmt.Errorf("failed to create environment credentials: %w", err)
+		}
+	}
+
+	for _, credential := range credentials {
+		binding, err := credential.Binding(g.environmentCredentialsDir)
+		if err != nil {
+			return err
+		}
+
+		bindings = append(bindings, binding)
+	}
+
 	if len(bindings) == 0 {
 		// If there are no bindings then we are done
 		return nil
@@ -43,17 +105,36 @@ func (g GitCredentialManager) Setup(workingDir, platformDir string) error {
 
 	g.logs.Process("Configuring credentials")
 
-	uniqueContext := map[string]interface{}{}
-
-	for _, b := range bindings {
-		context := "credential.helper"
+	// The contexts and expiry of every binding are checked before the git
+	// config is modified so that an expired credential fails the build early
+	contexts := make([]string, len(bindings))
+	for i, b := range bindings {
+		var domain string
 		if entry, ok := b.Entries["context"]; ok {
-			domain, err := entry.ReadString()
+			content, err := entry.ReadString()
 			if err != nil {
 				return err
 			}
 
-			context = fmt.Sprintf("credential.%s.helper", strings.TrimSpace(domain))
+			domain = strings.TrimSpace(content)
+		} else if defaults, ok := credentialProviderDefaults[strings.ToLower(b.Provider)]; ok {
+			domain = defaults.Context
+		}
+
+		err = g.checkExpiry(b, domain, config)
+		if err != nil {
+			return err
+		}
+
+		contexts[i] = domain
+	}
+
+	uniqueContext := map[string]interface{}{}
+
+	for i, b := range bindings {
+		mechanism, context, value, err := credentialConfig(b, contexts[i])
+		if err != nil {
+			return err
 		}
 
 		// Checks to see if the context is unique if not error because having
@@ -65,13 +146,19 @@ func (g GitCredentialManager) Setup(workingDir, platformDir string) error {
 			return fmt.Errorf("failed: there are two or more bindings for the same context: please limit the bindings to one per context")
 		}
 
+		display := "default"
+		if contexts[i] != "" {
+			display = RedactCredentials(contexts[i])
+		}
+		g.logs.Subprocess("binding=%s context=%s mechanism=%s", bindingName(b), display, mechanism)
+
 		buffer := bytes.NewBuffer(nil)
-		err := g.executable.Execute(pexec.Execution{
+		err = g.executable.Execute(pexec.Execution{
 			Args: []string{
 				"config",
 				"--global",
 				context,
-				fmt.Sprintf("!f() { cat %q; }; f", filepath.Join(b.Path, "credentials")),
+				value,
 			},
 			Dir:    workingDir,
 			Stdout: buffer,
@@ -79,7 +166,7 @@ func (g GitCredentialManager) Setup(workingDir, platformDir string) error {
 		})
 
 		if err != nil {
-			g.logs.Detail(buffer.String())
+			g.logs.Detail(RedactSecrets(buffer.String(), bindingSecrets(b)...))
 			return err
 		}
 
@@ -87,5 +174,267 @@ func (g GitCredentialManager) Setup(workingDir, platformDir string) error {
 
 	g.logs.Process("Added %d custom git credential manager(s) to the git config", len(uniqueContext))
 	g.logs.Break()
+
+	if config.CredentialCheck {
+		return g.checkCredentials(workingDir, bindings, contexts)
+	}
+
+	return nil
+}
+
+// credentialConfig returns the authentication mechanism of the binding along
+// with the git config key and value that configure it. The credentials entry,
+// or a credential record built from the token entry, is served by a
+// credential helper.
+func credentialConfig(binding servicebindings.Binding, context string) (mechanism, key, value string, err error) {
+	key = "credential.helper"
+	if context != "" {
+		key = fmt.Sprintf("credential.%s.helper", context)
+	}
+
+	_, hasCredentials := binding.Entries["credentials"]
+	if _, ok := binding.Entries["token"]; ok && !hasCredentials {
+		// A raw token is shaped into a credential record using the username
+		// expected by the provider
+		username := credentialProviderDefaults[strings.ToLower(binding.Provider)].Username
+		if entry, ok := binding.Entries["username"]; ok {
+			content, err := entry.ReadString()
+			if err != nil {
+				return "", "", "", err
+			}
+
+			username = strings.TrimSpace(content)
+		}
+
+		if username == "" {
+			return "", "", "", fmt.Errorf("failed to configure binding %s: a token requires a username entry or a known provider", binding.Path)
+		}
+
+		return CredentialMechanismHelper, key, fmt.Sprintf("!f() { echo %q; printf 'password=%%s\\n' \"$(cat %q)\"; }; f", "username="+username, filepath.Join(binding.Path, "token")), nil
+	}
+
+	return CredentialMechanismHelper, key, fmt.Sprintf("!f() { cat %q; }; f", filepath.Join(binding.Path, "credentials")), nil
+}
+
+// bindingName returns the name of the binding, falling back to the name of
+// its directory.
+func bindingName(binding servicebindings.Binding) string {
+	if binding.Name != "" {
+		return binding.Name
+	}
+
+	return filepath.Base(binding.Path)
+}
+
+// bindingSecrets returns the secret values held by the binding so that they
+// can be redacted from the git output.
+func bindingSecrets(binding servicebindings.Binding) []string {
+	var secrets []string
+
+	password, err := credentialPassword(binding)
+	if err == nil && password != "" {
+		secrets = append(secrets, password)
+	}
+
+	return secrets
+}
+
+// checkCredentials runs git ls-remote against the check-url entry of every
+// binding to verify that the configured credentials authenticate. A binding
+// without a check-url is checked against the smart-HTTP endpoint of its
+// context instead, which only tells whether the credentials are rejected.
+// Every binding is checked before failing so that the build log reports all
+// of the broken ones at once.
+func (g GitCredentialManager) checkCredentials(workingDir string, bindings []servicebindings.Binding, contexts []string) error {
+	g.logs.Process("Checking credentials")
+
+	var failures int
+	for i, b := range bindings {
+		entry, ok := b.Entries["check-url"]
+		if !ok {
+			passed, err := g.checkContext(workingDir, b, contexts[i])
+			if err != nil {
+				return err
+			}
+
+			if !passed {
+				failures++
+			}
+			continue
+		}
+
+		content, err := entry.ReadString()
+		if err != nil {
+			return err
+		}
+		uri := strings.TrimSpace(content)
+
+		buffer := bytes.NewBuffer(nil)
+		err = g.executable.Execute(pexec.Execution{
+			Args: []string{"ls-remote", "--heads", uri},
+			Dir:  workingDir,
+			// The check must never block on a terminal prompt when the
+			// credentials are rejected
+			Env:    append(os.Environ(), "GIT_TERMINAL_PROMPT=0"),
+			Stdout: io.Discard,
+			Stderr: buffer,
+		})
+		if err != nil {
+			g.logs.Subprocess("%s: failed", RedactCredentials(uri))
+			g.logs.Detail(RedactSecrets(buffer.String(), bindingSecrets(b)...))
+			failures++
+			continue
+		}
+
+		g.logs.Subprocess("%s: ok", RedactCredentials(uri))
+	}
+	g.logs.Break()
+
+	if failures > 0 {
+		return fmt.Errorf("failed to authenticate with %d credential(s)", failures)
+	}
+
+	return nil
+}
+
+// checkContext requests <context>/info/refs?service=git-upload-pack, as the
+// first request of a fetch, with the credentials that git serves for the
+// context. It returns false when the credentials are rejected. A context that
+// is not a single HTTP host or path, or that the server answers with anything
+// but a success or a rejection, cannot be checked and only logs a warning.
+func (g GitCredentialManager) checkContext(workingDir string, binding servicebindings.Binding, context string) (bool, error) {
+	scheme, _, _ := strings.Cut(context, "://")
+	if (scheme != "http" && scheme != "https") || strings.Contains(context, "*") {
+		g.logs.Subprocess("%s: Warning: not checked, the context is not an HTTP URL, add a check-url to check the credentials", binding.Path)
+		return true, nil
+	}
+
+	display := RedactCredentials(context)
+
+	buffer := bytes.NewBuffer(nil)
+	output := bytes.NewBuffer(nil)
+	err := g.executable.Execute(pexec.Execution{
+		Args:  []string{"credential", "fill"},
+		Dir:   workingDir,
+		Env:   append(os.Environ(), "GIT_TERMINAL_PROMPT=0"),
+		Stdin: strings.NewReader(fmt.Sprintf("url=%s\n\n", context)),
+		// The output holds the password and is never logged
+		Stdout: output,
+		Stderr: buffer,
+	})
+	if err != nil {
+		g.logs.Subprocess("%s: failed", display)
+		g.logs.Detail(RedactSecrets(buffer.String(), bindingSecrets(binding)...))
+		return false, nil
+	}
+
+	var username, password string
+	for _, line := range strings.Split(output.String(), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		switch key {
+		case "username":
+			username = value
+		case "password":
+			password = value
+		}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(context, "/")+"/info/refs?service=git-upload-pack", nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to check credentials of binding %s: %w", binding.Path, err)
+	}
+	req.SetBasicAuth(username, password)
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		g.logs.Subprocess("%s: Warning: not checked, the request failed, add a check-url to check the credentials", display)
+		g.logs.Detail(RedactSecrets(err.Error(), password))
+		return true, nil
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		g.logs.Subprocess("%s: failed", display)
+		g.logs.Detail("the server answered %s", resp.Status)
+		return false, nil
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		g.logs.Subprocess("%s: ok", display)
+		return true, nil
+	default:
+		g.logs.Subprocess("%s: Warning: not checked, the server answered %s, add a check-url to check the credentials", display, resp.Status)
+		return true, nil
+	}
+}
+
+// checkExpiry warns when the credentials of the binding are about to expire
+// and fails when they already have. The expiry is read from the expires-at
+// entry of the binding or, when enabled, from the API of the token provider.
+func (g GitCredentialManager) checkExpiry(binding servicebindings.Binding, context string, config Configuration) error {
+	var (
+		expiresAt time.Time
+		found     bool
+	)
+
+	if entry, ok := binding.Entries["expires-at"]; ok {
+		content, err := entry.ReadString()
+		if err != nil {
+			return err
+		}
+
+		expiresAt, err = parseExpiry(content)
+		if err != nil {
+			return fmt.Errorf("failed to parse expires-at of binding %s: %w", binding.Path, err)
+		}
+		found = true
+	} else if config.CredentialIntrospection {
+		apiURLs := map[string]string{
+			CredentialProviderGitHub: config.GitHubAPIURL,
+			CredentialProviderGitLab: config.GitLabAPIURL,
+		}
+
+		provider := credentialProvider(binding, context)
+		apiURL, ok := apiURLs[provider]
+		if !ok {
+			return nil
+		}
+
+		// The default API only serves the public host of the provider, so
+		// the token of any other host, e.g. GitHub Enterprise, must not be
+		// sent to it
+		defaults := credentialProviderDefaults[provider]
+		if apiURL == defaults.APIURL && !sameHost(context, defaults.Context) {
+			g.logs.Subprocess("%s: skipped expiry check, %s is not served by %s", binding.Path, RedactCredentials(context), apiURL)
+			return nil
+		}
+
+		token, err := credentialPassword(binding)
+		if err != nil {
+			return err
+		}
+
+		if token == "" {
+			return nil
+		}
+
+		expiresAt, found, err = g.introspector.ExpiresAt(provider, apiURL, token)
+		if err != nil {
+			return fmt.Errorf("failed to check expiry of binding %s: %w", binding.Path, err)
+		}
+	}
+
+	if !found {
+		return nil
+	}
+
+	now := g.clock.Now()
+	if !now.Before(expiresAt) {
+		return fmt.Errorf("failed: credentials in binding %s expired at %s", binding.Path, expiresAt.UTC().Format(time.RFC3339))
+	}
+
+	if remaining := expiresAt.Sub(now); remaining <= config.CredentialExpiryWarning {
+		g.logs.Subprocess("Warning: credentials in binding %s expire at %s (in %s)", binding.Path, expiresAt.UTC().Format(time.RFC3339), remaining.Truncate(time.Minute))
+	}
+
 	return nil
 }
@@ -0,0 +1,94 @@
+package git
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// RemoveGitDirectory deletes the .git entry of the repository rooted at dir
+// and returns the number of bytes that were removed.
+func RemoveGitDirectory(dir string) (int64, error) {
+	path := filepath.Join(dir, ".git")
+
+	size, err := directorySize(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove .git directory: %w", err)
+	}
+
+	err = os.RemoveAll(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to remove .git directory: %w", err)
+	}
+
+	return size, nil
+}
+
+// SanitizeGitDirectory removes the remotes, hooks and FETCH_HEAD of the
+// repository rooted at dir while keeping its history. It returns the number
+// of bytes that were removed.
+func SanitizeGitDirectory(executable Executable, logger scribe.Emitter, dir string) (int64, error) {
+	gitDir, err := runGit(executable, logger, dir, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return 0, err
+	}
+
+	before, err := directorySize(gitDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sanitize .git directory: %w", err)
+	}
+
+	output, err := runGit(executable, logger, dir, "remote")
+	if err != nil {
+		return 0, err
+	}
+
+	for _, remote := range lines(output) {
+		_, err = runGit(executable, logger, dir, "remote", "remove", remote)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, path := range []string{filepath.Join(gitDir, "hooks"), filepath.Join(gitDir, "FETCH_HEAD")} {
+		err = os.RemoveAll(path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to sanitize .git directory: %w", err)
+		}
+	}
+
+	after, err := directorySize(gitDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sanitize .git directory: %w", err)
+	}
+
+	return before - after, nil
+}
+
+func directorySize(path string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(path, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+
+			size += info.Size()
+		}
+
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+
+	return size, nil
+}
@@ -0,0 +1,121 @@
+package git_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/git/fakes"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testGitDirectory(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		dir string
+	)
+
+	it.Before(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "repository")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(dir, ".git", "hooks"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, ".git", "config"), []byte("0123456789"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, ".git", "hooks", "pre-commit"), []byte("01234"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, ".git", "FETCH_HEAD"), []byte("012"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "some-file"), []byte("some-content"), 0644)).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	context("RemoveGitDirectory", func() {
+		it("removes the .git directory and reports its size", func() {
+			size, err := git.RemoveGitDirectory(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(Equal(int64(18)))
+
+			Expect(filepath.Join(dir, ".git")).NotTo(BeADirectory())
+			Expect(filepath.Join(dir, "some-file")).To(BeARegularFile())
+		})
+
+		context("when there is no .git directory", func() {
+			it("removes nothing", func() {
+				size, err := git.RemoveGitDirectory(filepath.Join(dir, "missing"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(size).To(Equal(int64(0)))
+			})
+		})
+	})
+
+	context("SanitizeGitDirectory", func() {
+		var (
+			executable *fakes.Executable
+			executions []pexec.Execution
+		)
+
+		it.Before(func() {
+			executions = nil
+			executable = &fakes.Executable{}
+			executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				executions = append(executions, execution)
+				switch strings.Join(execution.Args, " ") {
+				case "rev-parse --absolute-git-dir":
+					fmt.Fprintln(execution.Stdout, filepath.Join(dir, ".git"))
+				case "remote":
+					fmt.Fprintln(execution.Stdout, "origin\nupstream")
+				}
+				return nil
+			}
+		})
+
+		it("removes the remotes, hooks and FETCH_HEAD", func() {
+			size, err := git.SanitizeGitDirectory(executable, scribe.NewEmitter(bytes.NewBuffer(nil)), dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(size).To(Equal(int64(8)))
+
+			Expect(executions).To(HaveLen(4))
+			Expect(executions[2].Args).To(Equal([]string{"remote", "remove", "origin"}))
+			Expect(executions[3].Args).To(Equal([]string{"remote", "remove", "upstream"}))
+
+			Expect(filepath.Join(dir, ".git", "hooks")).NotTo(BeADirectory())
+			Expect(filepath.Join(dir, ".git", "FETCH_HEAD")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(dir, ".git", "config")).To(BeARegularFile())
+		})
+
+		context("failure cases", func() {
+			context("when a remote cannot be removed", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						switch strings.Join(execution.Args, " ") {
+						case "rev-parse --absolute-git-dir":
+							fmt.Fprintln(execution.Stdout, filepath.Join(dir, ".git"))
+						case "remote":
+							fmt.Fprintln(execution.Stdout, "origin")
+						default:
+							return errors.New("some-error")
+						}
+						return nil
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := git.SanitizeGitDirectory(executable, scribe.NewEmitter(bytes.NewBuffer(nil)), dir)
+					Expect(err).To(MatchError("failed to execute 'git remote remove origin': some-error"))
+				})
+			})
+		})
+	})
+}
@@ -9,8 +9,29 @@ import (
 
 func TestUnitGit(t *testing.T) {
 	suite := spec.New("git", spec.Report(report.Terminal{}), spec.Parallel())
+	suite("Archive", testArchive)
 	suite("Build", testBuild)
+	suite("CIContext", testCIContext)
+	suite("Clean", testClean)
+	suite("Configuration", testConfiguration)
+	suite("CredentialExpiry", testCredentialExpiry)
 	suite("Detect", testDetect)
+	suite("Environment", testEnvironment)
+	suite("EnvironmentCredentials", testEnvironmentCredentials)
+	suite("GitDirectory", testGitDirectory)
 	suite("GitCredentialManager", testGitCredentialManager)
+	suite("LayerKey", testLayerKey)
+	suite("Metadata", testMetadata)
+	suite("MetadataSources", testMetadataSources)
+	suite("Mirrors", testMirrors)
+	suite("MTimes", testMTimes)
+	suite("Plan", testPlan)
+	suite("Repository", testRepository)
+	suite("SBOM", testSBOM)
+	suite("Source", testSource)
+	suite("SupplementarySources", testSupplementarySources)
+	suite("Secrets", testSecrets)
+	suite("Signature", testSignature)
+	suite("Tree", testTree)
 	suite.Run(t)
 }
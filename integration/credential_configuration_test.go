@@ -71,6 +71,7 @@ func testCredentialConfiguration(t *testing.T, context spec.G, it spec.S) {
 			Expect(logs).To(ContainLines(
 				MatchRegexp(fmt.Sprintf(`%s \d+\.\d+\.\d+`, settings.Buildpack.Name)),
 				"  Configuring credentials",
+				"    binding=credentials context=https://example.com mechanism=helper",
 				"  Added 1 custom git credential manager(s) to the git config",
 				"",
 				"Paketo Buildpack for Credential Fill",
@@ -65,11 +65,18 @@ func testDefault(t *testing.T, context spec.G, it spec.S) {
 
 			Expect(logs).To(ContainLines(
 				MatchRegexp(fmt.Sprintf(`%s \d+\.\d+\.\d+`, settings.Buildpack.Name)),
+				"  Found git repository",
+				"    Repository root: /workspace",
+				"    App path: .",
+				"",
 				"  Configuring build environment",
-				`    REVISION -> "2df6ac40991b695cc6c31faa79926980ff7dc0ff"`,
+				`    GIT_METADATA_FILE -> "/layers/paketo-buildpacks_git/git/git-metadata.toml"`,
+				`    REVISION          -> "2df6ac40991b695cc6c31faa79926980ff7dc0ff"`,
+				`    SOURCE_DATE_EPOCH -> "1620843106"`,
 				"",
 				"  Configuring launch environment",
-				`    REVISION -> "2df6ac40991b695cc6c31faa79926980ff7dc0ff"`,
+				`    GIT_METADATA_FILE -> "/layers/paketo-buildpacks_git/git/git-metadata.toml"`,
+				`    REVISION          -> "2df6ac40991b695cc6c31faa79926980ff7dc0ff"`,
 			))
 
 			Expect(image.Labels).To(HaveKeyWithValue("org.opencontainers.image.revision", "2df6ac40991b695cc6c31faa79926980ff7dc0ff"))
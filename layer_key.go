@@ -0,0 +1,145 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+)
+
+// LayerKey identifies the inputs that the git layer was built from. When the
+// key of a new build matches the one stored in the layer metadata of the
+// previous build, the layer is reused untouched.
+type LayerKey struct {
+	Revision string
+	Config   string
+	Bindings string
+	Metadata string
+}
+
+// NewLayerKey computes the key of the git layer from the metadata of the
+// repository, the BP_GIT_* configuration, the requested metadata fields and
+// the contents of the given service bindings. The parts of the CI context that
+// identify a single run are left out of the key.
+func NewLayerKey(environment Environment, fields MetadataFields, bindings []servicebindings.Binding, metadata Metadata) (LayerKey, error) {
+	key := LayerKey{Revision: metadata.Revision}
+
+	var err error
+	key.Config, err = sha256Hex(func(w io.Writer) error {
+		var names []string
+		for name := range environment {
+			// The credentials do not change the layer and their values must
+			// not be derivable from the layer metadata
+			if strings.HasPrefix(name, "BP_GIT_") && !strings.HasPrefix(name, EnvironmentCredentialPrefix) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Fprintf(w, "%s=%s\n", name, environment[name])
+		}
+		fmt.Fprintf(w, "fields=%s\n", fields)
+
+		return nil
+	})
+	if err != nil {
+		return LayerKey{}, err
+	}
+
+	key.Bindings, err = sha256Hex(func(w io.Writer) error {
+		for _, binding := range bindings {
+			fmt.Fprintf(w, "%s/%s/%s\n", binding.Name, binding.Type, binding.Provider)
+			for _, name := range entryNames(binding) {
+				content, err := binding.Entries[name].ReadBytes()
+				if err != nil {
+					return fmt.Errorf("failed to read binding entry %q: %w", name, err)
+				}
+
+				fmt.Fprintf(w, "%s:%x\n", name, sha256.Sum256(content))
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return LayerKey{}, err
+	}
+
+	// The pipeline URL and build number change with every run of the CI
+	// system and are refreshed in a reused layer instead
+	if metadata.CI != nil {
+		ci := *metadata.CI
+		ci.PipelineURL, ci.BuildNumber = "", ""
+		metadata.CI = &ci
+	}
+
+	key.Metadata, err = sha256Hex(func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(metadata)
+	})
+	if err != nil {
+		return LayerKey{}, err
+	}
+
+	return key, nil
+}
+
+// Matches reports whether the layer metadata was written for the same key.
+func (k LayerKey) Matches(layer packit.Layer) bool {
+	return layer.Metadata != nil &&
+		layer.Metadata["revision"] == k.Revision &&
+		layer.Metadata["config-sha256"] == k.Config &&
+		layer.Metadata["bindings-sha256"] == k.Bindings &&
+		layer.Metadata["metadata-sha256"] == k.Metadata
+}
+
+// LayerMetadata returns the layer metadata recording the key and the image
+// labels computed for it.
+func (k LayerKey) LayerMetadata(labels map[string]string) map[string]interface{} {
+	stored := map[string]interface{}{}
+	for name, value := range labels {
+		stored[name] = value
+	}
+
+	return map[string]interface{}{
+		"revision":        k.Revision,
+		"config-sha256":   k.Config,
+		"bindings-sha256": k.Bindings,
+		"metadata-sha256": k.Metadata,
+		"labels":          stored,
+	}
+}
+
+// StoredLabels returns the image labels recorded in the layer metadata.
+func StoredLabels(layer packit.Layer) map[string]string {
+	labels := map[string]string{}
+
+	stored, ok := layer.Metadata["labels"].(map[string]interface{})
+	if !ok {
+		return labels
+	}
+
+	for name, value := range stored {
+		if s, ok := value.(string); ok {
+			labels[name] = s
+		}
+	}
+
+	return labels
+}
+
+func sha256Hex(writeTo func(io.Writer) error) (string, error) {
+	hash := sha256.New()
+	err := writeTo(hash)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
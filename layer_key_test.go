@@ -0,0 +1,143 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testLayerKey(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		bindingDir string
+		bindings   []servicebindings.Binding
+		metadata   git.Metadata
+		key        git.LayerKey
+	)
+
+	it.Before(func() {
+		var err error
+		bindingDir, err = os.MkdirTemp("", "binding")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(bindingDir, "allowed_signers"), []byte("some-key"), 0600)).To(Succeed())
+
+		bindings = []servicebindings.Binding{
+			{
+				Name:    "some-binding",
+				Type:    "git-signing-keys",
+				Entries: map[string]*servicebindings.Entry{"allowed_signers": servicebindings.NewEntry(filepath.Join(bindingDir, "allowed_signers"))},
+			},
+		}
+
+		metadata = git.Metadata{Revision: "sha123456789", Branch: "main"}
+
+		key, err = git.NewLayerKey(git.Environment{"BP_GIT_ENV_PREFIX": "APP_", "PATH": "/bin"}, nil, bindings, metadata)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(bindingDir)).To(Succeed())
+	})
+
+	context("NewLayerKey", func() {
+		it("records the revision and digests of the inputs", func() {
+			Expect(key.Revision).To(Equal("sha123456789"))
+			Expect(key.Config).To(HaveLen(64))
+			Expect(key.Bindings).To(HaveLen(64))
+			Expect(key.Metadata).To(HaveLen(64))
+		})
+
+		it("ignores the variables that do not configure the buildpack", func() {
+			other, err := git.NewLayerKey(git.Environment{"BP_GIT_ENV_PREFIX": "APP_", "PATH": "/usr/bin"}, nil, bindings, metadata)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(other).To(Equal(key))
+		})
+
+		it("ignores the credentials given through environment variables", func() {
+			other, err := git.NewLayerKey(git.Environment{"BP_GIT_ENV_PREFIX": "APP_", "BP_GIT_CREDENTIALS_WORK": "some-token"}, nil, bindings, metadata)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(other).To(Equal(key))
+		})
+
+		it("changes with the configuration", func() {
+			other, err := git.NewLayerKey(git.Environment{"BP_GIT_ENV_PREFIX": "OTHER_"}, nil, bindings, metadata)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(other.Config).NotTo(Equal(key.Config))
+
+			other, err = git.NewLayerKey(git.Environment{"BP_GIT_ENV_PREFIX": "APP_"}, git.MetadataFields{"branch": true}, bindings, metadata)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(other.Config).NotTo(Equal(key.Config))
+		})
+
+		it("changes with the binding contents", func() {
+			Expect(os.WriteFile(filepath.Join(bindingDir, "allowed_signers"), []byte("other-key"), 0600)).To(Succeed())
+
+			other, err := git.NewLayerKey(git.Environment{"BP_GIT_ENV_PREFIX": "APP_"}, nil, bindings, metadata)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(other.Bindings).NotTo(Equal(key.Bindings))
+		})
+
+		it("changes with the metadata", func() {
+			dirty := true
+			metadata.Dirty = &dirty
+
+			other, err := git.NewLayerKey(git.Environment{"BP_GIT_ENV_PREFIX": "APP_"}, nil, bindings, metadata)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(other.Metadata).NotTo(Equal(key.Metadata))
+		})
+
+		it("ignores the run of the CI system", func() {
+			metadata.CI = &git.CIContext{Provider: "gitlab-ci", PullRequest: "42"}
+			first, err := git.NewLayerKey(git.Environment{"BP_GIT_ENV_PREFIX": "APP_"}, nil, bindings, metadata)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(first.Metadata).NotTo(Equal(key.Metadata))
+
+			metadata.CI = &git.CIContext{Provider: "gitlab-ci", PullRequest: "42", PipelineURL: "https://example.com/pipelines/2", BuildNumber: "2"}
+			second, err := git.NewLayerKey(git.Environment{"BP_GIT_ENV_PREFIX": "APP_"}, nil, bindings, metadata)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(second.Metadata).To(Equal(first.Metadata))
+			Expect(metadata.CI.BuildNumber).To(Equal("2"))
+		})
+
+		context("failure cases", func() {
+			context("when a binding entry cannot be read", func() {
+				it.Before(func() {
+					Expect(os.Remove(filepath.Join(bindingDir, "allowed_signers"))).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := git.NewLayerKey(git.Environment{}, nil, bindings, metadata)
+					Expect(err).To(MatchError(ContainSubstring(`failed to read binding entry "allowed_signers"`)))
+				})
+			})
+		})
+	})
+
+	context("Matches", func() {
+		it("matches the layer metadata written for the key", func() {
+			layer := packit.Layer{Metadata: key.LayerMetadata(map[string]string{"some-label": "some-value"})}
+			Expect(key.Matches(layer)).To(BeTrue())
+			Expect(git.StoredLabels(layer)).To(Equal(map[string]string{"some-label": "some-value"}))
+		})
+
+		it("does not match a layer without metadata", func() {
+			Expect(key.Matches(packit.Layer{})).To(BeFalse())
+			Expect(git.StoredLabels(packit.Layer{})).To(BeEmpty())
+		})
+
+		it("does not match a layer written for another revision", func() {
+			layer := packit.Layer{Metadata: key.LayerMetadata(nil)}
+			layer.Metadata["revision"] = "sha987654321"
+			Expect(key.Matches(layer)).To(BeFalse())
+		})
+	})
+}
@@ -0,0 +1,234 @@
+package git
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+const (
+	// MetadataFileTOML is the name of the TOML metadata file written into the
+	// git layer.
+	MetadataFileTOML = "git-metadata.toml"
+
+	// MetadataFileJSON is the name of the JSON metadata file written into the
+	// git layer.
+	MetadataFileJSON = "git-metadata.json"
+)
+
+// Metadata describes the state of the git repository that the application
+// was built from. It is written into the git layer so that other buildpacks
+// can consume it without calling git themselves.
+type Metadata struct {
+	Revision   string      `toml:"revision" json:"revision"`
+	Branch     string      `toml:"branch,omitempty" json:"branch,omitempty"`
+	Tags       []string    `toml:"tags,omitempty" json:"tags,omitempty"`
+	Remote     string      `toml:"remote,omitempty" json:"remote,omitempty"`
+	CommitTime string      `toml:"commit_time,omitempty" json:"commit_time,omitempty"`
+	Submodules []Submodule `toml:"submodules,omitempty" json:"submodules,omitempty"`
+
+	// Dirty reports whether the tracked files differ from HEAD. It is nil
+	// when the dirty field was not requested, as the files were not checked.
+	Dirty *bool `toml:"dirty,omitempty" json:"dirty,omitempty"`
+
+	// Source is the provider that the metadata was read from. It is one of
+	// the MetadataSource* values.
+	Source string `toml:"source,omitempty" json:"source,omitempty"`
+
+	// CI is the context of the CI run that built the application, if any.
+	CI *CIContext `toml:"ci,omitempty" json:"ci,omitempty"`
+
+	// Sources are the supplementary repositories that were cloned into the
+	// application source.
+	Sources []SupplementarySource `toml:"sources,omitempty" json:"sources,omitempty"`
+
+	// Tree compares the application source as it was built to the tree of
+	// HEAD.
+	Tree *TreeAttestation `toml:"tree,omitempty" json:"tree,omitempty"`
+}
+
+// Submodule describes a submodule checked out in the repository.
+type Submodule struct {
+	Path     string `toml:"path" json:"path"`
+	Revision string `toml:"revision" json:"revision"`
+}
+
+// MetadataCollector gathers Metadata by running git against a repository.
+type MetadataCollector struct {
+	executable Executable
+	logger     scribe.Emitter
+}
+
+func NewMetadataCollector(executable Executable, logger scribe.Emitter) MetadataCollector {
+	return MetadataCollector{
+		executable: executable,
+		logger:     logger,
+	}
+}
+
+// Collect returns the Metadata of the repository found in dir. Only the
+// given fields are computed; the revision is always included.
+func (c MetadataCollector) Collect(dir string, fields MetadataFields) (Metadata, error) {
+	var (
+		metadata Metadata
+		err      error
+	)
+
+	metadata.Revision, err = c.git(dir, "rev-parse", "HEAD")
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	if fields.Has(MetadataFieldBranch) {
+		branch, err := c.git(dir, "rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return Metadata{}, err
+		}
+
+		// A detached HEAD has no branch
+		if branch != "HEAD" {
+			metadata.Branch = branch
+		}
+	}
+
+	if fields.Has(MetadataFieldTags) {
+		tags, err := c.git(dir, "tag", "--points-at", "HEAD")
+		if err != nil {
+			return Metadata{}, err
+		}
+		metadata.Tags = lines(tags)
+	}
+
+	if fields.Has(MetadataFieldRemote) {
+		metadata.Remote, err = c.remote(dir)
+		if err != nil {
+			return Metadata{}, err
+		}
+	}
+
+	if fields.Has(MetadataFieldDirty) {
+		status, err := c.git(dir, "status", "--porcelain", "--untracked-files=no")
+		if err != nil {
+			return Metadata{}, err
+		}
+		dirty := status != ""
+		metadata.Dirty = &dirty
+	}
+
+	if fields.Has(MetadataFieldCommitTime) {
+		metadata.CommitTime, err = c.git(dir, "show", "-s", "--format=%cI", "HEAD")
+		if err != nil {
+			return Metadata{}, err
+		}
+	}
+
+	if fields.Has(MetadataFieldSubmodules) {
+		metadata.Submodules, err = c.submodules(dir)
+		if err != nil {
+			return Metadata{}, err
+		}
+	}
+
+	return metadata, nil
+}
+
+// remote returns the URL of the origin remote, falling back to the first
+// configured remote when there is no origin.
+func (c MetadataCollector) remote(dir string) (string, error) {
+	output, err := c.git(dir, "remote")
+	if err != nil {
+		return "", err
+	}
+
+	remotes := lines(output)
+	if len(remotes) == 0 {
+		return "", nil
+	}
+
+	name := remotes[0]
+	for _, remote := range remotes {
+		if remote == "origin" {
+			name = remote
+			break
+		}
+	}
+
+	return c.git(dir, "remote", "get-url", name)
+}
+
+func (c MetadataCollector) submodules(dir string) ([]Submodule, error) {
+	output, err := c.git(dir, "submodule", "status", "--recursive")
+	if err != nil {
+		return nil, err
+	}
+
+	var submodules []Submodule
+	for _, line := range lines(output) {
+		// Each line has the form "[ +-U]<sha> <path> (<describe>)"
+		fields := strings.Fields(strings.TrimLeft(line, " +-U"))
+		if len(fields) < 2 {
+			continue
+		}
+
+		submodules = append(submodules, Submodule{
+			Path:     fields[1],
+			Revision: fields[0],
+		})
+	}
+
+	return submodules, nil
+}
+
+// CommitTimestamp returns the committer timestamp of HEAD in seconds since
+// the Unix epoch.
+func (c MetadataCollector) CommitTimestamp(dir string) (string, error) {
+	return c.git(dir, "show", "-s", "--format=%ct", "HEAD")
+}
+
+func (c MetadataCollector) git(dir string, args ...string) (string, error) {
+	return runGit(c.executable, c.logger, dir, args...)
+}
+
+// WriteMetadata writes the metadata as both TOML and JSON into dir and returns
+// the path of the TOML file.
+func WriteMetadata(dir string, metadata Metadata) (string, error) {
+	tomlPath := filepath.Join(dir, MetadataFileTOML)
+	file, err := os.Create(tomlPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to write git metadata: %w", err)
+	}
+	defer file.Close()
+
+	err = toml.NewEncoder(file).Encode(metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to write git metadata: %w", err)
+	}
+
+	content, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to write git metadata: %w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, MetadataFileJSON), append(content, '\n'), 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write git metadata: %w", err)
+	}
+
+	return tomlPath, nil
+}
+
+func lines(output string) []string {
+	var result []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) != "" {
+			result = append(result, line)
+		}
+	}
+
+	return result
+}
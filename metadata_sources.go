@@ -0,0 +1,208 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The providers that the metadata can be read from, in order of precedence.
+const (
+	MetadataSourceGit           = "git"
+	MetadataSourceArchival      = "git-archival"
+	MetadataSourceGitHubActions = "github-actions"
+	MetadataSourceGitLabCI      = "gitlab-ci"
+	MetadataSourceJenkins       = "jenkins"
+	MetadataSourceTekton        = "tekton"
+	MetadataSourceEnvironment   = "environment"
+)
+
+// ArchivalFile is the file that git archive expands the commit details into
+// when it has the export-subst attribute.
+const ArchivalFile = ".git-archival.txt"
+
+// environmentSource describes the build-time environment variables that a
+// CI system passes the details of the checked out commit in.
+type environmentSource struct {
+	name       string
+	revision   string
+	branch     func(Environment) string
+	tag        func(Environment) string
+	remote     func(Environment) string
+	commitTime string
+}
+
+var environmentSources = []environmentSource{
+	{
+		name:     MetadataSourceGitHubActions,
+		revision: "GITHUB_SHA",
+		branch: func(environment Environment) string {
+			branch, _ := strings.CutPrefix(environment["GITHUB_REF"], "refs/heads/")
+			if branch == environment["GITHUB_REF"] {
+				return ""
+			}
+			return branch
+		},
+		tag: func(environment Environment) string {
+			tag, _ := strings.CutPrefix(environment["GITHUB_REF"], "refs/tags/")
+			if tag == environment["GITHUB_REF"] {
+				return ""
+			}
+			return tag
+		},
+		remote: func(environment Environment) string {
+			if environment["GITHUB_SERVER_URL"] == "" || environment["GITHUB_REPOSITORY"] == "" {
+				return ""
+			}
+			return fmt.Sprintf("%s/%s.git", environment["GITHUB_SERVER_URL"], environment["GITHUB_REPOSITORY"])
+		},
+	},
+	{
+		name:     MetadataSourceGitLabCI,
+		revision: "CI_COMMIT_SHA",
+		branch:   variable("CI_COMMIT_BRANCH"),
+		tag:      variable("CI_COMMIT_TAG"),
+		remote: func(environment Environment) string {
+			// CI_REPOSITORY_URL is not used as it embeds a job token
+			if environment["CI_PROJECT_URL"] == "" {
+				return ""
+			}
+			return environment["CI_PROJECT_URL"] + ".git"
+		},
+		commitTime: "CI_COMMIT_TIMESTAMP",
+	},
+	{
+		name:     MetadataSourceJenkins,
+		revision: "GIT_COMMIT",
+		branch: func(environment Environment) string {
+			return strings.TrimPrefix(environment["GIT_BRANCH"], "origin/")
+		},
+		remote: variable("GIT_URL"),
+	},
+	{
+		name:     MetadataSourceTekton,
+		revision: "CHAINS_GIT_COMMIT",
+		remote:   variable("CHAINS_GIT_URL"),
+	},
+	{
+		name:     MetadataSourceEnvironment,
+		revision: "BP_GIT_REVISION",
+		branch:   variable("BP_GIT_BRANCH"),
+		remote:   variable("BP_GIT_REMOTE"),
+	},
+}
+
+func variable(name string) func(Environment) string {
+	return func(environment Environment) string {
+		return environment[name]
+	}
+}
+
+// metadata returns the metadata found in the environment, if any.
+func (s environmentSource) metadata(environment Environment) (Metadata, bool) {
+	revision := strings.TrimSpace(environment[s.revision])
+	if revision == "" {
+		return Metadata{}, false
+	}
+
+	metadata := Metadata{
+		Revision:   revision,
+		CommitTime: environment[s.commitTime],
+		Source:     s.name,
+	}
+
+	if s.branch != nil {
+		metadata.Branch = s.branch(environment)
+	}
+
+	if s.remote != nil {
+		metadata.Remote = s.remote(environment)
+	}
+
+	if s.tag != nil {
+		if tag := s.tag(environment); tag != "" {
+			metadata.Tags = []string{tag}
+		}
+	}
+
+	return metadata, true
+}
+
+// FallbackMetadata returns the metadata of the application source when there
+// is no .git directory. It is read from the first of the .git-archival.txt
+// file in dir, the variables set by a CI system or BP_GIT_REVISION that
+// provides a revision. The returned boolean reports whether any of them did.
+func FallbackMetadata(dir string, environment Environment) (Metadata, bool, error) {
+	metadata, found, err := ArchivalMetadata(filepath.Join(dir, ArchivalFile))
+	if err != nil || found {
+		return metadata, found, err
+	}
+
+	for _, source := range environmentSources {
+		if metadata, ok := source.metadata(environment); ok {
+			return metadata, true, nil
+		}
+	}
+
+	return Metadata{}, false, nil
+}
+
+// ArchivalMetadata reads the metadata from a .git-archival.txt file expanded
+// by git archive. The file is ignored when it does not exist or has not been
+// expanded.
+func ArchivalMetadata(path string) (Metadata, bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Metadata{}, false, nil
+		}
+
+		return Metadata{}, false, fmt.Errorf("failed to read %s: %w", ArchivalFile, err)
+	}
+	defer file.Close()
+
+	metadata := Metadata{Source: MetadataSourceArchival}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, found := strings.Cut(scanner.Text(), ":")
+		if !found {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "node":
+			metadata.Revision = value
+		case "node-date":
+			metadata.CommitTime = value
+		case "ref-names":
+			// ref-names has the form "HEAD -> main, tag: v1.2.3, origin/main"
+			for _, ref := range strings.Split(value, ",") {
+				ref = strings.TrimSpace(ref)
+				if tag, ok := strings.CutPrefix(ref, "tag: "); ok {
+					metadata.Tags = append(metadata.Tags, tag)
+				} else if branch, ok := strings.CutPrefix(ref, "HEAD -> "); ok {
+					metadata.Branch = branch
+				}
+			}
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return Metadata{}, false, fmt.Errorf("failed to read %s: %w", ArchivalFile, err)
+	}
+
+	if metadata.Revision == "" || strings.Contains(metadata.Revision, "$Format") {
+		return Metadata{}, false, nil
+	}
+
+	if strings.Contains(metadata.CommitTime, "$Format") {
+		metadata.CommitTime = ""
+	}
+
+	return metadata, true, nil
+}
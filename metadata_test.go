@@ -0,0 +1,220 @@
+package git_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/git/fakes"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+	. "github.com/paketo-buildpacks/occam/matchers"
+)
+
+func testMetadata(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		executable *fakes.Executable
+		outputs    map[string]string
+		buffer     *bytes.Buffer
+
+		collector git.MetadataCollector
+	)
+
+	it.Before(func() {
+		outputs = map[string]string{
+			"rev-parse HEAD":                          "sha123456789\n",
+			"rev-parse --abbrev-ref HEAD":             "main\n",
+			"tag --points-at HEAD":                    "v1.2.3\nlatest\n",
+			"remote":                                  "upstream\norigin\n",
+			"remote get-url origin":                   "https://example.com/some-org/some-repo.git\n",
+			"status --porcelain --untracked-files=no": " M some-file\n",
+			"show -s --format=%cI HEAD":               "2023-01-02T03:04:05+00:00\n",
+			"submodule status --recursive":            " sha-abc some/submodule (heads/main)\n+sha-def other-submodule\n",
+			"show -s --format=%ct HEAD":               "1672628645\n",
+		}
+
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			fmt.Fprint(execution.Stdout, outputs[strings.Join(execution.Args, " ")])
+			return nil
+		}
+
+		buffer = bytes.NewBuffer(nil)
+		collector = git.NewMetadataCollector(executable, scribe.NewEmitter(buffer))
+	})
+
+	context("Collect", func() {
+		it("returns the repository metadata", func() {
+			metadata, err := collector.Collect("some-dir", nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			dirty := true
+			Expect(metadata).To(Equal(git.Metadata{
+				Revision:   "sha123456789",
+				Branch:     "main",
+				Tags:       []string{"v1.2.3", "latest"},
+				Remote:     "https://example.com/some-org/some-repo.git",
+				Dirty:      &dirty,
+				CommitTime: "2023-01-02T03:04:05+00:00",
+				Submodules: []git.Submodule{
+					{Path: "some/submodule", Revision: "sha-abc"},
+					{Path: "other-submodule", Revision: "sha-def"},
+				},
+			}))
+
+			Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal("some-dir"))
+		})
+
+		context("when only some fields are requested", func() {
+			it("only computes those fields", func() {
+				metadata, err := collector.Collect("some-dir", git.MetadataFields{"revision": true, "branch": true})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(metadata).To(Equal(git.Metadata{
+					Revision: "sha123456789",
+					Branch:   "main",
+				}))
+
+				Expect(executable.ExecuteCall.CallCount).To(Equal(2))
+			})
+		})
+
+		context("when HEAD is detached and there are no remotes", func() {
+			it.Before(func() {
+				outputs["rev-parse --abbrev-ref HEAD"] = "HEAD\n"
+				outputs["remote"] = ""
+				outputs["status --porcelain --untracked-files=no"] = ""
+			})
+
+			it("leaves the branch and remote empty", func() {
+				metadata, err := collector.Collect("some-dir", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(metadata.Branch).To(BeEmpty())
+				Expect(metadata.Remote).To(BeEmpty())
+				Expect(metadata.Dirty).NotTo(BeNil())
+				Expect(*metadata.Dirty).To(BeFalse())
+			})
+		})
+
+		context("when there is no origin remote", func() {
+			it.Before(func() {
+				outputs["remote"] = "upstream\n"
+				outputs["remote get-url upstream"] = "https://example.com/upstream.git\n"
+			})
+
+			it("uses the first remote", func() {
+				metadata, err := collector.Collect("some-dir", nil)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(metadata.Remote).To(Equal("https://example.com/upstream.git"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when a git command fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						if strings.Join(execution.Args, " ") == "tag --points-at HEAD" {
+							fmt.Fprintln(execution.Stderr, "some-stderr")
+							return errors.New("some-error")
+						}
+						return nil
+					}
+				})
+
+				it("returns an error and logs the output", func() {
+					_, err := collector.Collect("some-dir", nil)
+					Expect(err).To(MatchError("failed to execute 'git tag --points-at HEAD': some-error"))
+					Expect(buffer).To(ContainLines("        some-stderr"))
+				})
+			})
+		})
+	})
+
+	context("CommitTimestamp", func() {
+		it("returns the committer timestamp of HEAD", func() {
+			timestamp, err := collector.CommitTimestamp("some-dir")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(timestamp).To(Equal("1672628645"))
+
+			Expect(executable.ExecuteCall.Receives.Execution.Dir).To(Equal("some-dir"))
+		})
+	})
+
+	context("WriteMetadata", func() {
+		var layerDir string
+
+		it.Before(func() {
+			var err error
+			layerDir, err = os.MkdirTemp("", "layer")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(layerDir)).To(Succeed())
+		})
+
+		it("writes the metadata as TOML and JSON", func() {
+			dirty := true
+			metadata := git.Metadata{
+				Revision: "sha123456789",
+				Branch:   "main",
+				Tags:     []string{"v1.2.3"},
+				Dirty:    &dirty,
+				Submodules: []git.Submodule{
+					{Path: "some/submodule", Revision: "sha-abc"},
+				},
+			}
+
+			path, err := git.WriteMetadata(layerDir, metadata)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(layerDir, "git-metadata.toml")))
+
+			var fromTOML git.Metadata
+			_, err = toml.DecodeFile(path, &fromTOML)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fromTOML).To(Equal(metadata))
+
+			content, err := os.ReadFile(filepath.Join(layerDir, "git-metadata.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			var fromJSON git.Metadata
+			Expect(json.Unmarshal(content, &fromJSON)).To(Succeed())
+			Expect(fromJSON).To(Equal(metadata))
+		})
+
+		context("when the dirty flag was not checked", func() {
+			it("leaves it out", func() {
+				path, err := git.WriteMetadata(layerDir, git.Metadata{Revision: "sha123456789"})
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := os.ReadFile(path)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).NotTo(ContainSubstring("dirty"))
+
+				content, err = os.ReadFile(filepath.Join(layerDir, "git-metadata.json"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).NotTo(ContainSubstring("dirty"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the directory does not exist", func() {
+				it("returns an error", func() {
+					_, err := git.WriteMetadata(filepath.Join(layerDir, "missing"), git.Metadata{})
+					Expect(err).To(MatchError(ContainSubstring("failed to write git metadata")))
+				})
+			})
+		})
+	})
+}
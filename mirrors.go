@@ -0,0 +1,115 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+const (
+	// LayerNameGitMirrors is the name of the cache layer that the bare mirrors
+	// of BP_GIT_MIRRORS are kept in between builds.
+	LayerNameGitMirrors = "git-mirrors"
+)
+
+// SetupMirrors brings a bare mirror of every URL in cacheDir up to date and
+// rewrites the URL to its mirror in the global git config, so that the
+// fetches of the other buildpacks are served locally. The URL is rewritten
+// when it ends in .git or is followed by a slash. A mirror is cloned
+// once and then fetched incrementally. When the remote cannot be reached, a
+// mirror from a previous build is still used and a URL without one is left
+// alone. Mirrors of URLs that are no longer configured are removed. It
+// returns the number of URLs that are rewritten. It must be called before
+// the global git config rewrites any of the URLs.
+func SetupMirrors(executable Executable, logger scribe.Emitter, workingDir, cacheDir string, urls []string) (int, error) {
+	err := os.MkdirAll(cacheDir, os.ModePerm)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create %s: %w", cacheDir, err)
+	}
+
+	used := map[string]bool{}
+
+	var mirrors [][2]string
+	for _, url := range urls {
+		// The forms of a URL with and without .git or a trailing slash share
+		// a mirror
+		base := strings.TrimSuffix(strings.TrimSuffix(url, "/"), ".git")
+
+		name, err := sha256Hex(func(w io.Writer) error {
+			_, err := io.WriteString(w, base)
+			return err
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		name += ".git"
+		if used[name] {
+			continue
+		}
+		used[name] = true
+
+		mirror := filepath.Join(cacheDir, name)
+		_, err = os.Stat(mirror)
+		if err == nil {
+			_, err = runRemoteGit(executable, logger, mirror, "fetch", "--prune", "--quiet", "origin")
+			if err != nil {
+				logger.Subprocess("%s: Warning: failed to update, using the mirror from a previous build", RedactCredentials(url))
+			} else {
+				logger.Subprocess("%s: updated", RedactCredentials(url))
+			}
+		} else if os.IsNotExist(err) {
+			_, err = runRemoteGit(executable, logger, cacheDir, "clone", "--mirror", "--quiet", url, mirror)
+			if err != nil {
+				// A partial mirror must not be used by this or the next build
+				_ = os.RemoveAll(mirror)
+
+				logger.Subprocess("%s: Warning: failed to clone, it is fetched from the remote", RedactCredentials(url))
+				continue
+			}
+
+			logger.Subprocess("%s: cloned", RedactCredentials(url))
+		} else {
+			return 0, fmt.Errorf("failed to read mirror of %s: %w", RedactCredentials(url), err)
+		}
+
+		mirrors = append(mirrors, [2]string{mirror, base})
+	}
+
+	// The URLs are only rewritten once every mirror is up to date as the
+	// mirrors would otherwise be fetched from themselves. git rewrites every
+	// URL that starts with the given one, so the rewritten forms end in .git
+	// or a slash and are never the prefix of a sibling repository, e.g. repo
+	// of repo-tools.
+	for _, mirror := range mirrors {
+		_, err = runGit(executable, logger, workingDir, "config", "--global", fmt.Sprintf("url.file://%s.insteadOf", mirror[0]), mirror[1]+".git")
+		if err != nil {
+			return 0, err
+		}
+
+		_, err = runGit(executable, logger, workingDir, "config", "--global", fmt.Sprintf("url.file://%s/.insteadOf", mirror[0]), mirror[1]+"/")
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", cacheDir, err)
+	}
+
+	for _, entry := range entries {
+		if !used[entry.Name()] {
+			err = os.RemoveAll(filepath.Join(cacheDir, entry.Name()))
+			if err != nil {
+				return 0, fmt.Errorf("failed to remove unused mirror: %w", err)
+			}
+		}
+	}
+
+	return len(mirrors), nil
+}
@@ -0,0 +1,134 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// The modification times that tracked files can be restored to.
+const (
+	MTimeModeCommit = "commit"
+	MTimeModeHead   = "head"
+)
+
+// RestoreModificationTimes sets the modification time of every regular file
+// tracked below dir either to the committer time of the last commit that
+// touched it, or to the committer time of HEAD. The history is walked once
+// for all of the files. It returns the number of files that were updated.
+func RestoreModificationTimes(executable Executable, logger scribe.Emitter, dir, mode string) (int, error) {
+	output, err := runGitRaw(executable, logger, dir, "ls-files", "-z")
+	if err != nil {
+		return 0, err
+	}
+
+	var files []string
+	for _, file := range strings.Split(output, "\x00") {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+
+	var (
+		head  time.Time
+		times = map[string]time.Time{}
+	)
+
+	switch mode {
+	case MTimeModeHead:
+		output, err := runGit(executable, logger, dir, "show", "-s", "--format=%ct", "HEAD")
+		if err != nil {
+			return 0, err
+		}
+
+		head, err = parseUnixTime(output)
+		if err != nil {
+			return 0, err
+		}
+
+	case MTimeModeCommit:
+		// Each commit is printed as "\x01<time>\n<file>\x00<file>\x00...",
+		// newest first, so the first time seen for a file is the time of the
+		// last commit that touched it
+		output, err := runGitRaw(executable, logger, dir, "log", "-z", "--no-renames", "--relative", "--name-only", "--format=format:%x01%ct", "HEAD")
+		if err != nil {
+			return 0, err
+		}
+
+		var current time.Time
+		for _, token := range strings.Split(output, "\x00") {
+			if header, found := strings.CutPrefix(token, "\x01"); found {
+				var timestamp string
+				timestamp, token, _ = strings.Cut(header, "\n")
+
+				current, err = parseUnixTime(timestamp)
+				if err != nil {
+					return 0, err
+				}
+
+				if head.IsZero() {
+					head = current
+				}
+			}
+
+			if token == "" {
+				continue
+			}
+
+			if _, ok := times[token]; !ok {
+				times[token] = current
+			}
+		}
+
+	default:
+		return 0, fmt.Errorf("failed to restore modification times: unknown mode %q", mode)
+	}
+
+	var count int
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+
+		// Submodules and symbolic links are left alone, the latter so that
+		// files outside of the repository are never touched
+		info, err := os.Lstat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+
+			return 0, fmt.Errorf("failed to restore modification times: %w", err)
+		}
+
+		if !info.Mode().IsRegular() {
+			continue
+		}
+
+		mtime, ok := times[file]
+		if !ok {
+			mtime = head
+		}
+
+		err = os.Chtimes(path, mtime, mtime)
+		if err != nil {
+			return 0, fmt.Errorf("failed to restore modification times: %w", err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func parseUnixTime(value string) (time.Time, error) {
+	seconds, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse commit time %q: %w", value, err)
+	}
+
+	return time.Unix(seconds, 0), nil
+}
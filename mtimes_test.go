@@ -0,0 +1,148 @@
+package git_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/git/fakes"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testMTimes(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		workingDir string
+		executable *fakes.Executable
+		executions []pexec.Execution
+		outputs    map[string]string
+		logger     scribe.Emitter
+	)
+
+	it.Before(func() {
+		var err error
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(workingDir, "sub"), os.ModePerm)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(workingDir, "submodule"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "a"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, " sp ace"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "sub", "b"), nil, 0644)).To(Succeed())
+		Expect(os.Symlink(filepath.Join(workingDir, "a"), filepath.Join(workingDir, "link"))).To(Succeed())
+
+		outputs = map[string]string{
+			"ls-files -z": " sp ace\x00a\x00deleted\x00link\x00sub/b\x00submodule\x00",
+			"log -z --no-renames --relative --name-only --format=format:%x01%ct HEAD": "\x011100000000\n sp ace\x00sub/b\x00\x00\x011050000000\x00\x011000000000\na\x00sub/b\x00",
+			"show -s --format=%ct HEAD": "1100000000\n",
+		}
+
+		executions = nil
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			executions = append(executions, execution)
+			fmt.Fprint(execution.Stdout, outputs[strings.Join(execution.Args, " ")])
+			return nil
+		}
+
+		logger = scribe.NewEmitter(bytes.NewBuffer(nil))
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	mtime := func(path string) time.Time {
+		info, err := os.Lstat(filepath.Join(workingDir, path))
+		Expect(err).NotTo(HaveOccurred())
+		return info.ModTime()
+	}
+
+	context("RestoreModificationTimes", func() {
+		context("when restoring the times of the last commits", func() {
+			it("walks the history once and updates the tracked files", func() {
+				count, err := git.RestoreModificationTimes(executable, logger, workingDir, "commit")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(count).To(Equal(3))
+
+				Expect(executions).To(HaveLen(2))
+				Expect(executions[1].Dir).To(Equal(workingDir))
+
+				Expect(mtime(" sp ace")).To(Equal(time.Unix(1100000000, 0)))
+				Expect(mtime("sub/b")).To(Equal(time.Unix(1100000000, 0)))
+				Expect(mtime("a")).To(Equal(time.Unix(1000000000, 0)))
+				Expect(mtime("link")).NotTo(Equal(time.Unix(1000000000, 0)))
+			})
+
+			context("when a file does not appear in the history", func() {
+				it.Before(func() {
+					outputs["log -z --no-renames --relative --name-only --format=format:%x01%ct HEAD"] = "\x011100000000\n sp ace\x00sub/b\x00"
+				})
+
+				it("uses the time of HEAD", func() {
+					_, err := git.RestoreModificationTimes(executable, logger, workingDir, "commit")
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(mtime("a")).To(Equal(time.Unix(1100000000, 0)))
+				})
+			})
+		})
+
+		context("when restoring the time of HEAD", func() {
+			it("updates the tracked files", func() {
+				count, err := git.RestoreModificationTimes(executable, logger, workingDir, "head")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(count).To(Equal(3))
+
+				Expect(executions[1].Args).To(Equal([]string{"show", "-s", "--format=%ct", "HEAD"}))
+
+				Expect(mtime("a")).To(Equal(time.Unix(1100000000, 0)))
+				Expect(mtime("sub/b")).To(Equal(time.Unix(1100000000, 0)))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the mode is unknown", func() {
+				it("returns an error", func() {
+					_, err := git.RestoreModificationTimes(executable, logger, workingDir, "now")
+					Expect(err).To(MatchError(`failed to restore modification times: unknown mode "now"`))
+				})
+			})
+
+			context("when the commit time is malformed", func() {
+				it.Before(func() {
+					outputs["show -s --format=%ct HEAD"] = "yesterday"
+				})
+
+				it("returns an error", func() {
+					_, err := git.RestoreModificationTimes(executable, logger, workingDir, "head")
+					Expect(err).To(MatchError(ContainSubstring(`failed to parse commit time "yesterday"`)))
+				})
+			})
+
+			context("when git fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(pexec.Execution) error {
+						return errors.New("some-error")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := git.RestoreModificationTimes(executable, logger, workingDir, "commit")
+					Expect(err).To(MatchError("failed to execute 'git ls-files -z': some-error"))
+				})
+			})
+		})
+	})
+}
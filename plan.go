@@ -0,0 +1,142 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+const (
+	// PlanDependencyGitMetadata is the build plan entry provided when a git
+	// repository is available. Buildpacks that require it can request a
+	// subset of the metadata fields through the "fields" metadata key.
+	PlanDependencyGitMetadata = "git-metadata"
+
+	// PlanDependencyGitCredentials is the build plan entry provided when git
+	// credentials are configured through service bindings.
+	PlanDependencyGitCredentials = "git-credentials"
+)
+
+// The names of the Metadata fields that can be requested through the build
+// plan.
+const (
+	MetadataFieldRevision   = "revision"
+	MetadataFieldBranch     = "branch"
+	MetadataFieldTags       = "tags"
+	MetadataFieldRemote     = "remote"
+	MetadataFieldDirty      = "dirty"
+	MetadataFieldCommitTime = "commit_time"
+	MetadataFieldSubmodules = "submodules"
+)
+
+var metadataFields = []string{
+	MetadataFieldRevision,
+	MetadataFieldBranch,
+	MetadataFieldTags,
+	MetadataFieldRemote,
+	MetadataFieldDirty,
+	MetadataFieldCommitTime,
+	MetadataFieldSubmodules,
+}
+
+// MetadataFields is the set of Metadata fields that should be computed. A
+// nil set selects every field.
+type MetadataFields map[string]bool
+
+// Has reports whether the given field is part of the set.
+func (f MetadataFields) Has(field string) bool {
+	return f == nil || f[field]
+}
+
+// String returns the sorted, comma separated list of fields in the set.
+func (f MetadataFields) String() string {
+	if f == nil {
+		return strings.Join(metadataFields, ", ")
+	}
+
+	var fields []string
+	for field := range f {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	return strings.Join(fields, ", ")
+}
+
+// RequestedMetadataFields merges the "fields" requested by each git-metadata
+// entry in the buildpack plan. Every field is selected when there are no
+// git-metadata entries or when any of them does not restrict the fields. The
+// revision is always included.
+func RequestedMetadataFields(entries []packit.BuildpackPlanEntry) (MetadataFields, error) {
+	var (
+		fields MetadataFields
+		all    bool
+	)
+	for _, entry := range entries {
+		if entry.Name != PlanDependencyGitMetadata {
+			continue
+		}
+
+		// An entry without fields requires all of them, the fields of the
+		// other entries are still validated
+		value, ok := entry.Metadata["fields"]
+		if !ok {
+			all = true
+			continue
+		}
+
+		requested, err := toStringSlice(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s build plan entry: %w", PlanDependencyGitMetadata, err)
+		}
+
+		if fields == nil {
+			fields = MetadataFields{MetadataFieldRevision: true}
+		}
+
+		for _, field := range requested {
+			if !isMetadataField(field) {
+				return nil, fmt.Errorf("failed to parse %s build plan entry: unknown field %q", PlanDependencyGitMetadata, field)
+			}
+
+			fields[field] = true
+		}
+	}
+
+	if all {
+		return nil, nil
+	}
+
+	return fields, nil
+}
+
+func isMetadataField(field string) bool {
+	for _, f := range metadataFields {
+		if f == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func toStringSlice(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case []string:
+		return v, nil
+	case []interface{}:
+		var result []string
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("fields must be a list of strings")
+			}
+			result = append(result, s)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("fields must be a list of strings")
+	}
+}
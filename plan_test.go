@@ -0,0 +1,107 @@
+package git_test
+
+import (
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testPlan(t *testing.T, context spec.G, it spec.S) {
+	var Expect = NewWithT(t).Expect
+
+	context("RequestedMetadataFields", func() {
+		context("when there are no git-metadata entries", func() {
+			it("selects every field", func() {
+				fields, err := git.RequestedMetadataFields([]packit.BuildpackPlanEntry{
+					{Name: "git-credentials"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fields).To(BeNil())
+				Expect(fields.Has("submodules")).To(BeTrue())
+				Expect(fields.String()).To(Equal("revision, branch, tags, remote, dirty, commit_time, submodules"))
+			})
+		})
+
+		context("when the entries request fields", func() {
+			it("merges the requested fields", func() {
+				fields, err := git.RequestedMetadataFields([]packit.BuildpackPlanEntry{
+					{
+						Name:     "git-metadata",
+						Metadata: map[string]interface{}{"fields": []interface{}{"branch"}},
+					},
+					{
+						Name:     "git-metadata",
+						Metadata: map[string]interface{}{"fields": []string{"tags", "branch"}},
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fields).To(Equal(git.MetadataFields{
+					"revision": true,
+					"branch":   true,
+					"tags":     true,
+				}))
+				Expect(fields.Has("dirty")).To(BeFalse())
+				Expect(fields.String()).To(Equal("branch, revision, tags"))
+			})
+		})
+
+		context("when one of the entries does not restrict the fields", func() {
+			it("selects every field", func() {
+				fields, err := git.RequestedMetadataFields([]packit.BuildpackPlanEntry{
+					{
+						Name:     "git-metadata",
+						Metadata: map[string]interface{}{"fields": []interface{}{"branch"}},
+					},
+					{
+						Name: "git-metadata",
+					},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fields).To(BeNil())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when an entry after one without fields requests an unknown field", func() {
+				it("returns an error", func() {
+					_, err := git.RequestedMetadataFields([]packit.BuildpackPlanEntry{
+						{Name: "git-metadata"},
+						{
+							Name:     "git-metadata",
+							Metadata: map[string]interface{}{"fields": []interface{}{"author"}},
+						},
+					})
+					Expect(err).To(MatchError(`failed to parse git-metadata build plan entry: unknown field "author"`))
+				})
+			})
+
+			context("when the fields are not a list of strings", func() {
+				it("returns an error", func() {
+					_, err := git.RequestedMetadataFields([]packit.BuildpackPlanEntry{
+						{
+							Name:     "git-metadata",
+							Metadata: map[string]interface{}{"fields": []interface{}{1}},
+						},
+					})
+					Expect(err).To(MatchError("failed to parse git-metadata build plan entry: fields must be a list of strings"))
+				})
+			})
+
+			context("when the fields are a single string", func() {
+				it("returns an error", func() {
+					_, err := git.RequestedMetadataFields([]packit.BuildpackPlanEntry{
+						{
+							Name:     "git-metadata",
+							Metadata: map[string]interface{}{"fields": "branch"},
+						},
+					})
+					Expect(err).To(MatchError("failed to parse git-metadata build plan entry: fields must be a list of strings"))
+				})
+			})
+		})
+	})
+}
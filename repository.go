@@ -0,0 +1,110 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/fs"
+)
+
+// Repository describes where the git repository containing the application
+// source lives relative to the working directory.
+type Repository struct {
+	// Root is the directory that contains the .git entry.
+	Root string
+
+	// AppPath is the working directory relative to Root.
+	AppPath string
+}
+
+// FindRepository looks for a .git entry in the working directory. When
+// searchParents is set, it keeps walking up the parent directories until it
+// finds one or passes the ceiling directory. The returned boolean reports
+// whether a repository was found.
+func FindRepository(workingDir, ceiling string, searchParents bool) (Repository, bool, error) {
+	dir := filepath.Clean(workingDir)
+	ceiling = filepath.Clean(ceiling)
+
+	for {
+		exist, err := fs.Exists(filepath.Join(dir, ".git"))
+		if err != nil {
+			return Repository{}, false, err
+		}
+
+		if exist {
+			appPath, err := filepath.Rel(dir, filepath.Clean(workingDir))
+			if err != nil {
+				return Repository{}, false, err
+			}
+
+			return Repository{Root: dir, AppPath: appPath}, true, nil
+		}
+
+		if !searchParents || dir == ceiling || !isWithin(dir, ceiling) {
+			return Repository{}, false, nil
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return Repository{}, false, nil
+		}
+
+		dir = parent
+	}
+}
+
+func isWithin(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// GitDirs returns the git directory of the repository followed by its
+// common directory when the repository is a linked worktree. A .git file
+// points at the git directory through a "gitdir:" line.
+func (r Repository) GitDirs() ([]string, error) {
+	gitDir := filepath.Join(r.Root, ".git")
+
+	info, err := os.Stat(gitDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		content, err := os.ReadFile(gitDir)
+		if err != nil {
+			return nil, err
+		}
+
+		path, found := strings.CutPrefix(strings.TrimSpace(string(content)), "gitdir:")
+		if !found {
+			return nil, fmt.Errorf("failed to parse %s: missing gitdir", gitDir)
+		}
+
+		gitDir = resolvePath(r.Root, strings.TrimSpace(path))
+	}
+
+	dirs := []string{gitDir}
+
+	content, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err == nil {
+		dirs = append(dirs, resolvePath(gitDir, strings.TrimSpace(string(content))))
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	return dirs, nil
+}
+
+func resolvePath(base, path string) string {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+
+	return filepath.Join(base, path)
+}
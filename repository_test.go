@@ -0,0 +1,155 @@
+package git_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testRepository(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		root   string
+		appDir string
+	)
+
+	it.Before(func() {
+		var err error
+		root, err = os.MkdirTemp("", "repository")
+		Expect(err).NotTo(HaveOccurred())
+
+		appDir = filepath.Join(root, "some", "app")
+		Expect(os.MkdirAll(appDir, os.ModePerm)).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(root)).To(Succeed())
+	})
+
+	context("GitDirs", func() {
+		context("when .git is a directory", func() {
+			it.Before(func() {
+				Expect(os.Mkdir(filepath.Join(appDir, ".git"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns the .git directory", func() {
+				dirs, err := git.Repository{Root: appDir}.GitDirs()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dirs).To(Equal([]string{filepath.Join(appDir, ".git")}))
+			})
+		})
+
+		context("when .git is a file pointing at a worktree git directory", func() {
+			it.Before(func() {
+				worktreeDir := filepath.Join(root, "main", ".git", "worktrees", "app")
+				Expect(os.MkdirAll(worktreeDir, os.ModePerm)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(worktreeDir, "commondir"), []byte("../..\n"), 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(appDir, ".git"), []byte("gitdir: ../../main/.git/worktrees/app\n"), 0644)).To(Succeed())
+			})
+
+			it("returns the git directory and the common directory", func() {
+				dirs, err := git.Repository{Root: appDir}.GitDirs()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(dirs).To(Equal([]string{
+					filepath.Join(root, "main", ".git", "worktrees", "app"),
+					filepath.Join(root, "main", ".git"),
+				}))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the .git file is malformed", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(appDir, ".git"), []byte("not-a-gitdir"), 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := git.Repository{Root: appDir}.GitDirs()
+					Expect(err).To(MatchError(ContainSubstring("missing gitdir")))
+				})
+			})
+		})
+	})
+
+	context("FindRepository", func() {
+		context("when the .git directory is in the working directory", func() {
+			it.Before(func() {
+				Expect(os.Mkdir(filepath.Join(appDir, ".git"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns the working directory as the root", func() {
+				repository, found, err := git.FindRepository(appDir, "/", false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(repository).To(Equal(git.Repository{
+					Root:    appDir,
+					AppPath: ".",
+				}))
+			})
+		})
+
+		context("when .git is a file", func() {
+			it.Before(func() {
+				Expect(os.WriteFile(filepath.Join(appDir, ".git"), []byte("gitdir: /some/worktree"), 0600)).To(Succeed())
+			})
+
+			it("finds the repository", func() {
+				_, found, err := git.FindRepository(appDir, "/", false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeTrue())
+			})
+		})
+
+		context("when the .git directory is in a parent directory", func() {
+			it.Before(func() {
+				Expect(os.Mkdir(filepath.Join(root, ".git"), os.ModePerm)).To(Succeed())
+			})
+
+			it("returns the parent as the root", func() {
+				repository, found, err := git.FindRepository(appDir, "/", true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeTrue())
+				Expect(repository).To(Equal(git.Repository{
+					Root:    root,
+					AppPath: filepath.Join("some", "app"),
+				}))
+			})
+
+			it("searches the ceiling directory itself", func() {
+				_, found, err := git.FindRepository(appDir, root, true)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(found).To(BeTrue())
+			})
+
+			context("when searching parents is disabled", func() {
+				it("does not find the repository", func() {
+					_, found, err := git.FindRepository(appDir, "/", false)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(found).To(BeFalse())
+				})
+			})
+
+			context("when the ceiling is below the repository root", func() {
+				it("does not find the repository", func() {
+					_, found, err := git.FindRepository(appDir, filepath.Join(root, "some"), true)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(found).To(BeFalse())
+				})
+			})
+
+			context("when the working directory is outside of the ceiling", func() {
+				it("only searches the working directory", func() {
+					_, found, err := git.FindRepository(appDir, filepath.Join(root, "other"), true)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(found).To(BeFalse())
+				})
+			})
+		})
+	})
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"net/http"
 	"os"
+	"time"
 
 	"github.com/paketo-buildpacks/git"
 	"github.com/paketo-buildpacks/packit/v2"
+	"github.com/paketo-buildpacks/packit/v2/chronos"
 	"github.com/paketo-buildpacks/packit/v2/pexec"
 	"github.com/paketo-buildpacks/packit/v2/scribe"
 	"github.com/paketo-buildpacks/packit/v2/servicebindings"
@@ -14,12 +17,19 @@ func main() {
 	executable := pexec.NewExecutable("git")
 	emitter := scribe.NewEmitter(os.Stdout).WithLevel(os.Getenv("BP_LOG_LEVEL"))
 	bindingResolver := servicebindings.NewResolver()
+	environment := git.NewEnvironment(os.Environ())
+
+	// An unreachable token API must not hang the build
+	client := &http.Client{Timeout: 30 * time.Second}
 
 	packit.Run(
-		git.Detect(bindingResolver),
+		git.Detect(environment, bindingResolver),
 		git.Build(
+			environment,
+			bindingResolver,
 			executable,
-			git.NewGitCredentialManager(bindingResolver, executable, emitter),
+			git.NewGitCredentialManager(bindingResolver, executable, git.NewAPITokenIntrospector(client), chronos.DefaultClock, emitter),
+			git.NewGitSignatureVerifier(bindingResolver, executable, pexec.NewExecutable("gpg"), emitter),
 			emitter,
 		),
 	)
@@ -0,0 +1,209 @@
+package git
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2"
+)
+
+// The SBOM media types that the source description can be generated in.
+const (
+	CycloneDXFormat = "application/vnd.cyclonedx+json"
+	SPDXFormat      = "application/spdx+json"
+)
+
+// ProvenanceFile is the name of the file in the git layer that holds the
+// in-toto/SLSA materials describing the source.
+const ProvenanceFile = "git-provenance.json"
+
+// Material is an in-toto/SLSA material identifying the source repository
+// and the commit that the image was built from.
+type Material struct {
+	URI    string            `json:"uri"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance is the document written to ProvenanceFile.
+type Provenance struct {
+	Materials []Material `json:"materials"`
+}
+
+// NewMaterial describes the source repository located at root as a
+// material.
+func NewMaterial(metadata Metadata, root string) Material {
+	uri := "git+" + sourceURL(metadata, root)
+	if metadata.Branch != "" {
+		uri = fmt.Sprintf("%s@refs/heads/%s", uri, metadata.Branch)
+	}
+
+	return Material{
+		URI:    uri,
+		Digest: map[string]string{digestAlgorithm(metadata.Revision): metadata.Revision},
+	}
+}
+
+// WriteProvenance writes the materials describing the source into dir.
+func WriteProvenance(dir string, metadata Metadata, root string) error {
+	content, err := json.MarshalIndent(Provenance{
+		Materials: []Material{NewMaterial(metadata, root)},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+
+	err = os.WriteFile(filepath.Join(dir, ProvenanceFile), append(content, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write provenance: %w", err)
+	}
+
+	return nil
+}
+
+// GenerateSourceSBOM describes the source repository located at root in
+// each of the given SBOM media types.
+func GenerateSourceSBOM(metadata Metadata, root string, mediaTypes []string) (packit.SBOMFormats, error) {
+	var formats packit.SBOMFormats
+	for _, mediaType := range mediaTypes {
+		var (
+			document  interface{}
+			extension string
+		)
+
+		switch mediaType {
+		case CycloneDXFormat:
+			document, extension = cycloneDXDocument(metadata, root), "cdx.json"
+		case SPDXFormat:
+			document, extension = spdxDocument(metadata, root), "spdx.json"
+		default:
+			return nil, fmt.Errorf("unsupported SBOM format: %q", mediaType)
+		}
+
+		content, err := json.Marshal(document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate SBOM: %w", err)
+		}
+
+		formats = append(formats, packit.SBOMFormat{
+			Extension: extension,
+			Content:   bytes.NewReader(content),
+		})
+	}
+
+	return formats, nil
+}
+
+func cycloneDXDocument(metadata Metadata, root string) map[string]interface{} {
+	vcs := sourceURL(metadata, root)
+
+	return map[string]interface{}{
+		"bomFormat":   "CycloneDX",
+		"specVersion": "1.4",
+		"version":     1,
+		"components": []interface{}{
+			map[string]interface{}{
+				"bom-ref": "source",
+				"type":    "application",
+				"name":    sourceName(metadata, root),
+				"version": metadata.Revision,
+				"purl":    sourcePURL(metadata, root),
+				"externalReferences": []interface{}{
+					map[string]interface{}{"type": "vcs", "url": vcs},
+				},
+				"pedigree": map[string]interface{}{
+					"commits": []interface{}{
+						map[string]interface{}{"uid": metadata.Revision, "url": vcs},
+					},
+				},
+			},
+		},
+	}
+}
+
+func spdxDocument(metadata Metadata, root string) map[string]interface{} {
+	// The commit time keeps the document reproducible for a given commit
+	created := metadata.CommitTime
+	if created == "" {
+		created = "1980-01-01T00:00:01Z"
+	}
+
+	return map[string]interface{}{
+		"spdxVersion":       "SPDX-2.3",
+		"dataLicense":       "CC0-1.0",
+		"SPDXID":            "SPDXRef-DOCUMENT",
+		"name":              sourceName(metadata, root),
+		"documentNamespace": fmt.Sprintf("https://paketo.io/git/%s", metadata.Revision),
+		"creationInfo": map[string]interface{}{
+			"created":  created,
+			"creators": []string{"Tool: paketo-buildpacks/git"},
+		},
+		"packages": []interface{}{
+			map[string]interface{}{
+				"SPDXID":                "SPDXRef-Source",
+				"name":                  sourceName(metadata, root),
+				"versionInfo":           metadata.Revision,
+				"downloadLocation":      fmt.Sprintf("git+%s@%s", sourceURL(metadata, root), metadata.Revision),
+				"filesAnalyzed":         false,
+				"licenseConcluded":      "NOASSERTION",
+				"licenseDeclared":       "NOASSERTION",
+				"copyrightText":         "NOASSERTION",
+				"primaryPackagePurpose": "SOURCE",
+				"externalRefs": []interface{}{
+					map[string]interface{}{
+						"referenceCategory": "PACKAGE-MANAGER",
+						"referenceType":     "purl",
+						"referenceLocator":  sourcePURL(metadata, root),
+					},
+				},
+			},
+		},
+		"relationships": []interface{}{
+			map[string]interface{}{
+				"spdxElementId":      "SPDXRef-DOCUMENT",
+				"relationshipType":   "DESCRIBES",
+				"relatedSpdxElement": "SPDXRef-Source",
+			},
+		},
+	}
+}
+
+// sourceURL returns the remote URL of the repository, or a file URL of its
+// root when there is no remote.
+func sourceURL(metadata Metadata, root string) string {
+	if metadata.Remote != "" {
+		return metadata.Remote
+	}
+
+	return (&url.URL{Scheme: "file", Path: root}).String()
+}
+
+// sourceName derives a name for the source from the last element of its URL.
+func sourceName(metadata Metadata, root string) string {
+	name := filepath.Base(root)
+	if metadata.Remote != "" {
+		remote := strings.TrimSuffix(strings.TrimRight(metadata.Remote, "/"), ".git")
+		// scp-like remotes such as git@host:org/repo use a colon separator
+		name = path.Base(strings.ReplaceAll(remote, ":", "/"))
+	}
+
+	return name
+}
+
+func sourcePURL(metadata Metadata, root string) string {
+	return fmt.Sprintf("pkg:generic/%s@%s?vcs_url=%s", url.PathEscape(sourceName(metadata, root)), metadata.Revision,
+		url.QueryEscape(fmt.Sprintf("git+%s@%s", sourceURL(metadata, root), metadata.Revision)))
+}
+
+func digestAlgorithm(revision string) string {
+	if len(revision) == 64 {
+		return "sha256"
+	}
+
+	return "sha1"
+}
@@ -0,0 +1,169 @@
+package git_test
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSBOM(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		metadata git.Metadata
+	)
+
+	it.Before(func() {
+		metadata = git.Metadata{
+			Revision:   "2df6ac40991b695cc6c31faa79926980ff7dc0ff",
+			Branch:     "main",
+			Remote:     "https://example.com/some-org/some-repo.git",
+			CommitTime: "2023-01-02T03:04:05+00:00",
+		}
+	})
+
+	context("NewMaterial", func() {
+		it("describes the repository and commit", func() {
+			Expect(git.NewMaterial(metadata, "/workspace")).To(Equal(git.Material{
+				URI:    "git+https://example.com/some-org/some-repo.git@refs/heads/main",
+				Digest: map[string]string{"sha1": "2df6ac40991b695cc6c31faa79926980ff7dc0ff"},
+			}))
+		})
+
+		context("when there is no remote or branch and the repository uses sha256", func() {
+			it("uses the repository root", func() {
+				metadata.Remote = ""
+				metadata.Branch = ""
+				metadata.Revision = "1bc7c7ee7e5d2ae8bb8b09b2c5fa1ba1e6e6b3d0c6b1dd0e4ac8e7b6d0d5f4a2"
+
+				Expect(git.NewMaterial(metadata, "/workspace")).To(Equal(git.Material{
+					URI:    "git+file:///workspace",
+					Digest: map[string]string{"sha256": metadata.Revision},
+				}))
+			})
+		})
+	})
+
+	context("WriteProvenance", func() {
+		var layerDir string
+
+		it.Before(func() {
+			var err error
+			layerDir, err = os.MkdirTemp("", "layer")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(layerDir)).To(Succeed())
+		})
+
+		it("writes the materials", func() {
+			Expect(git.WriteProvenance(layerDir, metadata, "/workspace")).To(Succeed())
+
+			content, err := os.ReadFile(filepath.Join(layerDir, "git-provenance.json"))
+			Expect(err).NotTo(HaveOccurred())
+
+			var provenance git.Provenance
+			Expect(json.Unmarshal(content, &provenance)).To(Succeed())
+			Expect(provenance.Materials).To(Equal([]git.Material{git.NewMaterial(metadata, "/workspace")}))
+		})
+
+		context("failure cases", func() {
+			context("when the directory does not exist", func() {
+				it("returns an error", func() {
+					err := git.WriteProvenance(filepath.Join(layerDir, "missing"), metadata, "/workspace")
+					Expect(err).To(MatchError(ContainSubstring("failed to write provenance")))
+				})
+			})
+		})
+	})
+
+	context("GenerateSourceSBOM", func() {
+		it("generates a CycloneDX and SPDX source description", func() {
+			formats, err := git.GenerateSourceSBOM(metadata, "/workspace", []string{
+				"application/vnd.cyclonedx+json",
+				"application/spdx+json",
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(formats).To(HaveLen(2))
+
+			Expect(formats[0].Extension).To(Equal("cdx.json"))
+			content, err := io.ReadAll(formats[0].Content)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(MatchJSON(`{
+				"bomFormat": "CycloneDX",
+				"specVersion": "1.4",
+				"version": 1,
+				"components": [
+					{
+						"bom-ref": "source",
+						"type": "application",
+						"name": "some-repo",
+						"version": "2df6ac40991b695cc6c31faa79926980ff7dc0ff",
+						"purl": "pkg:generic/some-repo@2df6ac40991b695cc6c31faa79926980ff7dc0ff?vcs_url=git%2Bhttps%3A%2F%2Fexample.com%2Fsome-org%2Fsome-repo.git%402df6ac40991b695cc6c31faa79926980ff7dc0ff",
+						"externalReferences": [
+							{"type": "vcs", "url": "https://example.com/some-org/some-repo.git"}
+						],
+						"pedigree": {
+							"commits": [
+								{"uid": "2df6ac40991b695cc6c31faa79926980ff7dc0ff", "url": "https://example.com/some-org/some-repo.git"}
+							]
+						}
+					}
+				]
+			}`))
+
+			Expect(formats[1].Extension).To(Equal("spdx.json"))
+			content, err = io.ReadAll(formats[1].Content)
+			Expect(err).NotTo(HaveOccurred())
+
+			var spdx struct {
+				SPDXVersion  string `json:"spdxVersion"`
+				CreationInfo struct {
+					Created string `json:"created"`
+				} `json:"creationInfo"`
+				Packages []struct {
+					Name             string `json:"name"`
+					VersionInfo      string `json:"versionInfo"`
+					DownloadLocation string `json:"downloadLocation"`
+				} `json:"packages"`
+			}
+			Expect(json.Unmarshal(content, &spdx)).To(Succeed())
+			Expect(spdx.SPDXVersion).To(Equal("SPDX-2.3"))
+			Expect(spdx.CreationInfo.Created).To(Equal("2023-01-02T03:04:05+00:00"))
+			Expect(spdx.Packages).To(HaveLen(1))
+			Expect(spdx.Packages[0].Name).To(Equal("some-repo"))
+			Expect(spdx.Packages[0].VersionInfo).To(Equal("2df6ac40991b695cc6c31faa79926980ff7dc0ff"))
+			Expect(spdx.Packages[0].DownloadLocation).To(Equal("git+https://example.com/some-org/some-repo.git@2df6ac40991b695cc6c31faa79926980ff7dc0ff"))
+		})
+
+		context("when the remote is an scp-like URL", func() {
+			it("derives the name from the path", func() {
+				metadata.Remote = "git@example.com:some-org/other-repo.git"
+
+				formats, err := git.GenerateSourceSBOM(metadata, "/workspace", []string{"application/spdx+json"})
+				Expect(err).NotTo(HaveOccurred())
+
+				content, err := io.ReadAll(formats[0].Content)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(string(content)).To(ContainSubstring(`"name":"other-repo"`))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the format is not supported", func() {
+				it("returns an error", func() {
+					_, err := git.GenerateSourceSBOM(metadata, "/workspace", []string{"application/vnd.syft+json"})
+					Expect(err).To(MatchError(`unsupported SBOM format: "application/vnd.syft+json"`))
+				})
+			})
+		})
+	})
+}
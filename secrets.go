@@ -0,0 +1,169 @@
+package git
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// The policies applied when credentials are found in the .git directory.
+const (
+	SecretPolicyWarn   = "warn"
+	SecretPolicyFail   = "fail"
+	SecretPolicyIgnore = "ignore"
+)
+
+// Redacted replaces secrets in logs, metadata and labels.
+const Redacted = "REDACTED"
+
+// urlUserInfo matches the user information of a URL, capturing the scheme,
+// the username and the optional password.
+var urlUserInfo = regexp.MustCompile(`([A-Za-z][A-Za-z0-9+.-]*://)([^/@\s:]*)(:[^/@\s]*)?@`)
+
+// tokenPrefixes are the prefixes of access tokens that are commonly used as
+// the username of a URL.
+var tokenPrefixes = []string{"ghp_", "gho_", "ghu_", "ghs_", "ghr_", "github_pat_", "glpat-"}
+
+// CredentialFinding describes a URL with embedded credentials found in a git
+// file.
+type CredentialFinding struct {
+	// File is the path of the file containing the credentials.
+	File string
+
+	// Line is the 1-based line number of the credentials in File.
+	Line int
+
+	// URL is the offending URL with the credentials redacted.
+	URL string
+}
+
+// ScanForCredentials inspects the config, FETCH_HEAD and packed-refs files
+// of the given git directories for URLs with embedded credentials.
+func ScanForCredentials(gitDirs ...string) ([]CredentialFinding, error) {
+	var findings []CredentialFinding
+	for _, gitDir := range gitDirs {
+		for _, name := range []string{"config", "FETCH_HEAD", "packed-refs"} {
+			found, err := scanFile(filepath.Join(gitDir, name))
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan for credentials: %w", err)
+			}
+
+			findings = append(findings, found...)
+		}
+	}
+
+	return findings, nil
+}
+
+func scanFile(path string) ([]CredentialFinding, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var findings []CredentialFinding
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1024*1024)
+	for number := 1; scanner.Scan(); number++ {
+		line := scanner.Text()
+		for _, match := range urlUserInfo.FindAllStringSubmatch(line, -1) {
+			if !isCredential(match[1], match[2], match[3]) {
+				continue
+			}
+
+			findings = append(findings, CredentialFinding{
+				File: path,
+				Line: number,
+				URL:  RedactCredentials(strings.Fields(line[strings.Index(line, match[0]):])[0]),
+			})
+		}
+	}
+
+	return findings, scanner.Err()
+}
+
+// RedactCredentials replaces the passwords and access tokens embedded in any
+// URL found in the given text. The whole user information of an HTTP URL is
+// redacted, as tokens are commonly given as its username.
+func RedactCredentials(text string) string {
+	return urlUserInfo.ReplaceAllStringFunc(text, func(userInfo string) string {
+		match := urlUserInfo.FindStringSubmatch(userInfo)
+		scheme, username, password := match[1], match[2], match[3]
+
+		switch {
+		case isHTTP(scheme) && (username != "" || password != ""), isToken(username):
+			return fmt.Sprintf("%s%s@", scheme, Redacted)
+		case password != "":
+			return fmt.Sprintf("%s%s:%s@", scheme, username, Redacted)
+		default:
+			return userInfo
+		}
+	})
+}
+
+// stripCredentials removes the credentials that RedactCredentials redacts
+// from the URLs in text, keeping a username that is not a secret, e.g. the
+// git of ssh://git@host.
+func stripCredentials(text string) string {
+	return urlUserInfo.ReplaceAllStringFunc(text, func(userInfo string) string {
+		match := urlUserInfo.FindStringSubmatch(userInfo)
+		scheme, username, password := match[1], match[2], match[3]
+
+		if isCredential(scheme, username, password) {
+			return scheme
+		}
+
+		return userInfo
+	})
+}
+
+// RedactSecrets redacts the credentials embedded in URLs along with every
+// occurrence of the given secret values.
+func RedactSecrets(text string, secrets ...string) string {
+	text = RedactCredentials(text)
+
+	// Longer secrets are replaced first so that a secret containing another
+	// one is not left partially visible
+	secrets = append([]string(nil), secrets...)
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	for _, secret := range secrets {
+		if secret != "" {
+			text = strings.ReplaceAll(text, secret, Redacted)
+		}
+	}
+
+	return text
+}
+
+func isCredential(scheme, username, password string) bool {
+	return password != "" || isToken(username) || (isHTTP(scheme) && username != "")
+}
+
+// isHTTP reports whether the scheme, including the :// separator, is HTTP,
+// e.g. https:// or git+https://.
+func isHTTP(scheme string) bool {
+	scheme = strings.ToLower(strings.TrimSuffix(scheme, "://"))
+	if _, transport, found := strings.Cut(scheme, "+"); found {
+		scheme = transport
+	}
+
+	return scheme == "http" || scheme == "https"
+}
+
+func isToken(username string) bool {
+	for _, prefix := range tokenPrefixes {
+		if strings.HasPrefix(username, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
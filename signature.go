@@ -0,0 +1,293 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+)
+
+// The signature formats that commits can be verified against.
+const (
+	SignatureModeGPG = "gpg"
+	SignatureModeSSH = "ssh"
+	SignatureModeOff = "off"
+)
+
+// The outcomes of a signature verification.
+const (
+	SignatureStatusGood      = "good"
+	SignatureStatusBad       = "bad"
+	SignatureStatusUntrusted = "untrusted"
+	SignatureStatusUnsigned  = "unsigned"
+)
+
+var sshGoodSignature = regexp.MustCompile(`^Good "git" signature for (.+) with (.+ key \S+)$`)
+
+// Signature is the result of verifying the signature of a git object.
+type Signature struct {
+	// Status is one of the SignatureStatus* values.
+	Status string
+
+	// Signer is the identity of the trusted key that made the signature.
+	Signer string
+
+	// Key identifies the key that made the signature.
+	Key string
+
+	// Ref is the name of the verified tag, if any.
+	Ref string
+}
+
+// SignatureReport holds the signatures verified for a build.
+type SignatureReport struct {
+	Commit Signature
+	Tag    Signature
+}
+
+// GitSignatureVerifier verifies commit and tag signatures against the keys
+// provided through git-signing-keys service bindings.
+type GitSignatureVerifier struct {
+	bindingResolver BindingResolver
+	git             Executable
+	gpg             Executable
+	logs            scribe.Emitter
+}
+
+func NewGitSignatureVerifier(bindingResolver BindingResolver, git, gpg Executable, logs scribe.Emitter) GitSignatureVerifier {
+	return GitSignatureVerifier{
+		bindingResolver: bindingResolver,
+		git:             git,
+		gpg:             gpg,
+		logs:            logs,
+	}
+}
+
+// Verify checks the signature of HEAD, and of the tags pointing at HEAD when
+// verifyTags is set, using the given signature mode. Verification failures
+// are reported through the Status of the returned signatures; an error is
+// only returned when the verification could not be performed.
+func (v GitSignatureVerifier) Verify(workingDir, platformPath, mode string, verifyTags bool) (SignatureReport, error) {
+	bindings, err := v.bindingResolver.Resolve("git-signing-keys", "", platformPath)
+	if err != nil {
+		return SignatureReport{}, err
+	}
+
+	if len(bindings) == 0 {
+		return SignatureReport{}, fmt.Errorf("failed to verify signature: no git-signing-keys service bindings present")
+	}
+
+	keysDir, err := os.MkdirTemp("", "git-signing-keys")
+	if err != nil {
+		return SignatureReport{}, err
+	}
+	defer os.RemoveAll(keysDir)
+
+	var (
+		args []string
+		env  []string
+	)
+
+	switch mode {
+	case SignatureModeGPG:
+		env, err = v.importGPGKeys(keysDir, bindings)
+	case SignatureModeSSH:
+		args, err = v.writeAllowedSigners(keysDir, bindings)
+	default:
+		err = fmt.Errorf("unsupported signature mode %q", mode)
+	}
+	if err != nil {
+		return SignatureReport{}, fmt.Errorf("failed to verify signature: %w", err)
+	}
+
+	var report SignatureReport
+	report.Commit = v.verify(workingDir, mode, append(args, "verify-commit", "--raw", "HEAD"), env)
+
+	if verifyTags {
+		report.Tag = Signature{Status: SignatureStatusUnsigned}
+
+		output := bytes.NewBuffer(nil)
+		err = v.git.Execute(pexec.Execution{
+			Args:   []string{"tag", "--points-at", "HEAD"},
+			Dir:    workingDir,
+			Stdout: output,
+			Stderr: output,
+		})
+		if err != nil {
+			v.logs.Detail(output.String())
+			return SignatureReport{}, fmt.Errorf("failed to execute 'git tag --points-at HEAD': %w", err)
+		}
+
+		for i, tag := range lines(output.String()) {
+			signature := v.verify(workingDir, mode, append(args, "verify-tag", "--raw", tag), env)
+			signature.Ref = tag
+
+			if i == 0 || signature.Status == SignatureStatusGood {
+				report.Tag = signature
+			}
+
+			if signature.Status == SignatureStatusGood {
+				break
+			}
+		}
+	}
+
+	return report, nil
+}
+
+func (v GitSignatureVerifier) verify(workingDir, mode string, args, env []string) Signature {
+	output := bytes.NewBuffer(nil)
+	err := v.git.Execute(pexec.Execution{
+		Args:   args,
+		Dir:    workingDir,
+		Env:    env,
+		Stdout: output,
+		Stderr: output,
+	})
+
+	if mode == SignatureModeGPG {
+		return parseGPGStatus(output.String(), err)
+	}
+
+	return parseSSHStatus(output.String(), err)
+}
+
+// importGPGKeys imports every entry of the bindings into a dedicated keyring
+// and returns the environment that points git at that keyring.
+func (v GitSignatureVerifier) importGPGKeys(keysDir string, bindings []servicebindings.Binding) ([]string, error) {
+	err := os.Chmod(keysDir, 0700)
+	if err != nil {
+		return nil, err
+	}
+
+	env := append(os.Environ(), fmt.Sprintf("GNUPGHOME=%s", keysDir))
+	for _, binding := range bindings {
+		for _, name := range entryNames(binding) {
+			if name == "allowed_signers" {
+				continue
+			}
+
+			output := bytes.NewBuffer(nil)
+			err := v.gpg.Execute(pexec.Execution{
+				Args:   []string{"--batch", "--import", filepath.Join(binding.Path, name)},
+				Env:    env,
+				Stdout: output,
+				Stderr: output,
+			})
+			if err != nil {
+				v.logs.Detail(output.String())
+				return nil, fmt.Errorf("failed to import key %q: %w", filepath.Join(binding.Path, name), err)
+			}
+		}
+	}
+
+	return env, nil
+}
+
+// writeAllowedSigners merges the allowed_signers entries of the bindings and
+// returns the git arguments that configure SSH signature verification.
+func (v GitSignatureVerifier) writeAllowedSigners(keysDir string, bindings []servicebindings.Binding) ([]string, error) {
+	var signers []string
+	for _, binding := range bindings {
+		entry, ok := binding.Entries["allowed_signers"]
+		if !ok {
+			continue
+		}
+
+		content, err := entry.ReadString()
+		if err != nil {
+			return nil, err
+		}
+
+		signers = append(signers, strings.TrimSpace(content))
+	}
+
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no git-signing-keys service binding has an allowed_signers entry")
+	}
+
+	path := filepath.Join(keysDir, "allowed_signers")
+	err := os.WriteFile(path, []byte(strings.Join(signers, "\n")+"\n"), 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{"-c", "gpg.format=ssh", "-c", fmt.Sprintf("gpg.ssh.allowedSignersFile=%s", path)}, nil
+}
+
+// parseGPGStatus interprets the machine-readable status lines printed by
+// git when given --raw.
+func parseGPGStatus(output string, err error) Signature {
+	signature := Signature{Status: SignatureStatusUnsigned}
+	for _, line := range lines(output) {
+		fields := strings.Fields(strings.TrimPrefix(line, "[GNUPG:] "))
+		if len(fields) < 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case "GOODSIG":
+			signature.Status = SignatureStatusGood
+			signature.Signer = strings.Join(fields[2:], " ")
+		case "VALIDSIG":
+			signature.Key = fields[1]
+		case "BADSIG", "REVKEYSIG":
+			// A signature made by a revoked key must not be trusted even
+			// though it is cryptographically valid
+			signature.Status = SignatureStatusBad
+			signature.Signer = strings.Join(fields[2:], " ")
+		case "EXPKEYSIG", "EXPSIG":
+			signature.Status = SignatureStatusUntrusted
+			signature.Signer = strings.Join(fields[2:], " ")
+		case "ERRSIG", "NO_PUBKEY":
+			signature.Status = SignatureStatusUntrusted
+			signature.Key = fields[1]
+		}
+	}
+
+	if err != nil && signature.Status == SignatureStatusGood {
+		signature.Status = SignatureStatusBad
+	}
+
+	return signature
+}
+
+// parseSSHStatus interprets the output of ssh-keygen as printed by git.
+func parseSSHStatus(output string, err error) Signature {
+	if strings.TrimSpace(output) == "" {
+		return Signature{Status: SignatureStatusUnsigned}
+	}
+
+	for _, line := range lines(output) {
+		if matches := sshGoodSignature.FindStringSubmatch(strings.TrimSpace(line)); matches != nil && err == nil {
+			return Signature{
+				Status: SignatureStatusGood,
+				Signer: matches[1],
+				Key:    matches[2],
+			}
+		}
+	}
+
+	if strings.Contains(output, "No principal matched") {
+		return Signature{Status: SignatureStatusUntrusted}
+	}
+
+	return Signature{Status: SignatureStatusBad}
+}
+
+func entryNames(binding servicebindings.Binding) []string {
+	var names []string
+	for name := range binding.Entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
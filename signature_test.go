@@ -0,0 +1,368 @@
+package git_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/git/fakes"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSignature(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		bindingResolver *fakes.BindingResolver
+		gitExecutable   *fakes.Executable
+		gpgExecutable   *fakes.Executable
+
+		gitExecutions []pexec.Execution
+		gpgExecutions []pexec.Execution
+		outputs       map[string]string
+		failures      map[string]bool
+
+		bindingDir string
+
+		verifier git.GitSignatureVerifier
+	)
+
+	it.Before(func() {
+		var err error
+		bindingDir, err = os.MkdirTemp("", "binding")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.WriteFile(filepath.Join(bindingDir, "alice.asc"), []byte("some-key"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(bindingDir, "allowed_signers"), []byte("alice@example.com ssh-ed25519 AAAA\n"), 0600)).To(Succeed())
+
+		bindingResolver = &fakes.BindingResolver{}
+		bindingResolver.ResolveCall.Returns.BindingSlice = []servicebindings.Binding{
+			{
+				Path: bindingDir,
+				Entries: map[string]*servicebindings.Entry{
+					"alice.asc":       servicebindings.NewEntry(filepath.Join(bindingDir, "alice.asc")),
+					"allowed_signers": servicebindings.NewEntry(filepath.Join(bindingDir, "allowed_signers")),
+				},
+			},
+		}
+
+		gitExecutions = nil
+		gpgExecutions = nil
+		outputs = map[string]string{}
+		failures = map[string]bool{}
+
+		gitExecutable = &fakes.Executable{}
+		gitExecutable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			gitExecutions = append(gitExecutions, execution)
+
+			// Strip the -c configuration flags to find the subcommand
+			args := execution.Args
+			for len(args) > 1 && args[0] == "-c" {
+				args = args[2:]
+			}
+
+			key := strings.Join(args, " ")
+			fmt.Fprint(execution.Stderr, outputs[key])
+			if failures[key] {
+				return errors.New("exit status 1")
+			}
+
+			return nil
+		}
+
+		gpgExecutable = &fakes.Executable{}
+		gpgExecutable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			gpgExecutions = append(gpgExecutions, execution)
+			return nil
+		}
+
+		verifier = git.NewGitSignatureVerifier(bindingResolver, gitExecutable, gpgExecutable, scribe.NewEmitter(bytes.NewBuffer(nil)))
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(bindingDir)).To(Succeed())
+	})
+
+	context("Verify", func() {
+		context("when the mode is gpg", func() {
+			it.Before(func() {
+				outputs["verify-commit --raw HEAD"] = strings.Join([]string{
+					"[GNUPG:] NEWSIG",
+					"[GNUPG:] GOODSIG 39A3C0A2C8E8974C Alice <alice@example.com>",
+					"[GNUPG:] VALIDSIG 43A4EBB82A9D1E1030AB33B239A3C0A2C8E8974C 2023-01-02 1672628645 0 4 0 22 8 00 43A4EBB82A9D1E1030AB33B239A3C0A2C8E8974C",
+					"[GNUPG:] TRUST_UNDEFINED 0 pgp",
+				}, "\n")
+			})
+
+			it("imports the keys into a dedicated keyring and verifies HEAD", func() {
+				report, err := verifier.Verify("working-dir", "some-platform", "gpg", false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report).To(Equal(git.SignatureReport{
+					Commit: git.Signature{
+						Status: "good",
+						Signer: "Alice <alice@example.com>",
+						Key:    "43A4EBB82A9D1E1030AB33B239A3C0A2C8E8974C",
+					},
+				}))
+
+				Expect(bindingResolver.ResolveCall.Receives.Typ).To(Equal("git-signing-keys"))
+				Expect(bindingResolver.ResolveCall.Receives.PlatformDir).To(Equal("some-platform"))
+
+				Expect(gpgExecutions).To(HaveLen(1))
+				Expect(gpgExecutions[0].Args).To(Equal([]string{"--batch", "--import", filepath.Join(bindingDir, "alice.asc")}))
+				Expect(gpgExecutions[0].Env).To(ContainElement(HavePrefix("GNUPGHOME=")))
+
+				Expect(gitExecutions).To(HaveLen(1))
+				Expect(gitExecutions[0].Args).To(Equal([]string{"verify-commit", "--raw", "HEAD"}))
+				Expect(gitExecutions[0].Dir).To(Equal("working-dir"))
+				Expect(gitExecutions[0].Env).To(ContainElement(gpgExecutions[0].Env[len(gpgExecutions[0].Env)-1]))
+			})
+
+			context("when the key is not trusted", func() {
+				it.Before(func() {
+					outputs["verify-commit --raw HEAD"] = strings.Join([]string{
+						"[GNUPG:] NEWSIG",
+						"[GNUPG:] ERRSIG 39A3C0A2C8E8974C 22 8 00 1672628645 9 43A4EBB82A9D1E1030AB33B239A3C0A2C8E8974C",
+						"[GNUPG:] NO_PUBKEY 39A3C0A2C8E8974C",
+					}, "\n")
+					failures["verify-commit --raw HEAD"] = true
+				})
+
+				it("reports an untrusted signature", func() {
+					report, err := verifier.Verify("working-dir", "some-platform", "gpg", false)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.Commit).To(Equal(git.Signature{
+						Status: "untrusted",
+						Key:    "39A3C0A2C8E8974C",
+					}))
+				})
+			})
+
+			context("when the signature is bad", func() {
+				it.Before(func() {
+					outputs["verify-commit --raw HEAD"] = "[GNUPG:] BADSIG 39A3C0A2C8E8974C Alice <alice@example.com>"
+					failures["verify-commit --raw HEAD"] = true
+				})
+
+				it("reports a bad signature", func() {
+					report, err := verifier.Verify("working-dir", "some-platform", "gpg", false)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.Commit.Status).To(Equal("bad"))
+				})
+			})
+
+			context("when the key was revoked", func() {
+				it.Before(func() {
+					outputs["verify-commit --raw HEAD"] = strings.Join([]string{
+						"[GNUPG:] NEWSIG",
+						"[GNUPG:] KEYREVOKED",
+						"[GNUPG:] REVKEYSIG 39A3C0A2C8E8974C Alice <alice@example.com>",
+						"[GNUPG:] VALIDSIG 43A4EBB82A9D1E1030AB33B239A3C0A2C8E8974C 2023-01-02 1672628645 0 4 0 22 8 00 43A4EBB82A9D1E1030AB33B239A3C0A2C8E8974C",
+					}, "\n")
+				})
+
+				it("reports a bad signature", func() {
+					report, err := verifier.Verify("working-dir", "some-platform", "gpg", false)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.Commit).To(Equal(git.Signature{
+						Status: "bad",
+						Signer: "Alice <alice@example.com>",
+						Key:    "43A4EBB82A9D1E1030AB33B239A3C0A2C8E8974C",
+					}))
+				})
+			})
+
+			context("when the key has expired", func() {
+				it.Before(func() {
+					outputs["verify-commit --raw HEAD"] = strings.Join([]string{
+						"[GNUPG:] NEWSIG",
+						"[GNUPG:] KEYEXPIRED 1672628645",
+						"[GNUPG:] EXPKEYSIG 39A3C0A2C8E8974C Alice <alice@example.com>",
+						"[GNUPG:] VALIDSIG 43A4EBB82A9D1E1030AB33B239A3C0A2C8E8974C 2023-01-02 1672628645 0 4 0 22 8 00 43A4EBB82A9D1E1030AB33B239A3C0A2C8E8974C",
+					}, "\n")
+				})
+
+				it("reports an untrusted signature", func() {
+					report, err := verifier.Verify("working-dir", "some-platform", "gpg", false)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.Commit).To(Equal(git.Signature{
+						Status: "untrusted",
+						Signer: "Alice <alice@example.com>",
+						Key:    "43A4EBB82A9D1E1030AB33B239A3C0A2C8E8974C",
+					}))
+				})
+			})
+
+			context("when the signature has expired", func() {
+				it.Before(func() {
+					outputs["verify-commit --raw HEAD"] = "[GNUPG:] EXPSIG 39A3C0A2C8E8974C Alice <alice@example.com>"
+				})
+
+				it("reports an untrusted signature", func() {
+					report, err := verifier.Verify("working-dir", "some-platform", "gpg", false)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.Commit.Status).To(Equal("untrusted"))
+				})
+			})
+
+			context("when the commit is not signed", func() {
+				it.Before(func() {
+					outputs["verify-commit --raw HEAD"] = ""
+					failures["verify-commit --raw HEAD"] = true
+				})
+
+				it("reports an unsigned commit", func() {
+					report, err := verifier.Verify("working-dir", "some-platform", "gpg", false)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.Commit).To(Equal(git.Signature{Status: "unsigned"}))
+				})
+			})
+		})
+
+		context("when the mode is ssh", func() {
+			it.Before(func() {
+				outputs["verify-commit --raw HEAD"] = `Good "git" signature for alice@example.com with ED25519 key SHA256:abc`
+			})
+
+			it("writes the allowed signers and verifies HEAD", func() {
+				report, err := verifier.Verify("working-dir", "some-platform", "ssh", false)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(report.Commit).To(Equal(git.Signature{
+					Status: "good",
+					Signer: "alice@example.com",
+					Key:    "ED25519 key SHA256:abc",
+				}))
+
+				Expect(gpgExecutions).To(BeEmpty())
+				Expect(gitExecutions).To(HaveLen(1))
+				Expect(gitExecutions[0].Args[:3]).To(Equal([]string{"-c", "gpg.format=ssh", "-c"}))
+				Expect(gitExecutions[0].Args[3]).To(HavePrefix("gpg.ssh.allowedSignersFile="))
+				Expect(gitExecutions[0].Args[4:]).To(Equal([]string{"verify-commit", "--raw", "HEAD"}))
+			})
+
+			context("when no principal matches", func() {
+				it.Before(func() {
+					outputs["verify-commit --raw HEAD"] = "Good \"git\" signature with ED25519 key SHA256:abc\nNo principal matched."
+					failures["verify-commit --raw HEAD"] = true
+				})
+
+				it("reports an untrusted signature", func() {
+					report, err := verifier.Verify("working-dir", "some-platform", "ssh", false)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.Commit.Status).To(Equal("untrusted"))
+				})
+			})
+
+			context("when tags are verified", func() {
+				it.Before(func() {
+					gitExecutable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						gitExecutions = append(gitExecutions, execution)
+
+						switch execution.Args[len(execution.Args)-1] {
+						case "HEAD":
+							if execution.Args[0] == "tag" {
+								fmt.Fprintln(execution.Stdout, "v1.0.0\nv1.2.3")
+								return nil
+							}
+							fmt.Fprint(execution.Stderr, `Good "git" signature for alice@example.com with ED25519 key SHA256:abc`)
+						case "v1.2.3":
+							fmt.Fprint(execution.Stderr, `Good "git" signature for bob@example.com with ED25519 key SHA256:def`)
+						default:
+							return errors.New("exit status 1")
+						}
+
+						return nil
+					}
+				})
+
+				it("reports the first tag with a good signature", func() {
+					report, err := verifier.Verify("working-dir", "some-platform", "ssh", true)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.Tag).To(Equal(git.Signature{
+						Status: "good",
+						Signer: "bob@example.com",
+						Key:    "ED25519 key SHA256:def",
+						Ref:    "v1.2.3",
+					}))
+				})
+			})
+
+			context("when tags are verified but there are none", func() {
+				it("reports an unsigned tag", func() {
+					report, err := verifier.Verify("working-dir", "some-platform", "ssh", true)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(report.Tag).To(Equal(git.Signature{Status: "unsigned"}))
+				})
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the binding resolver fails", func() {
+				it.Before(func() {
+					bindingResolver.ResolveCall.Returns.Error = errors.New("failed to resolve bindings")
+				})
+
+				it("returns an error", func() {
+					_, err := verifier.Verify("working-dir", "some-platform", "gpg", false)
+					Expect(err).To(MatchError("failed to resolve bindings"))
+				})
+			})
+
+			context("when there are no bindings", func() {
+				it.Before(func() {
+					bindingResolver.ResolveCall.Returns.BindingSlice = nil
+				})
+
+				it("returns an error", func() {
+					_, err := verifier.Verify("working-dir", "some-platform", "gpg", false)
+					Expect(err).To(MatchError("failed to verify signature: no git-signing-keys service bindings present"))
+				})
+			})
+
+			context("when a key cannot be imported", func() {
+				it.Before(func() {
+					gpgExecutable.ExecuteCall.Stub = func(pexec.Execution) error {
+						return errors.New("some-error")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := verifier.Verify("working-dir", "some-platform", "gpg", false)
+					Expect(err).To(MatchError(ContainSubstring("failed to verify signature: failed to import key")))
+				})
+			})
+
+			context("when there is no allowed_signers entry", func() {
+				it.Before(func() {
+					bindingResolver.ResolveCall.Returns.BindingSlice[0].Entries = map[string]*servicebindings.Entry{}
+				})
+
+				it("returns an error", func() {
+					_, err := verifier.Verify("working-dir", "some-platform", "ssh", false)
+					Expect(err).To(MatchError("failed to verify signature: no git-signing-keys service binding has an allowed_signers entry"))
+				})
+			})
+
+			context("when the tags cannot be listed", func() {
+				it.Before(func() {
+					failures["tag --points-at HEAD"] = true
+				})
+
+				it("returns an error", func() {
+					_, err := verifier.Verify("working-dir", "some-platform", "ssh", true)
+					Expect(err).To(MatchError("failed to execute 'git tag --points-at HEAD': exit status 1"))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,260 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// Source is a remote repository that the application source is cloned from
+// when the platform only provides its URL.
+type Source struct {
+	// URL is the location of the repository.
+	URL string
+
+	// Ref is the branch or tag that is checked out. The default branch of the
+	// repository is checked out when it is empty.
+	Ref string
+
+	// Commit is the full SHA that the checked out commit must match. When
+	// Ref is empty, the commit itself is fetched.
+	Commit string
+
+	// Depth is the number of commits fetched, or 0 for the full history.
+	Depth int
+}
+
+// ResolveSource returns the repository configured through BP_GIT_SOURCE_URL
+// or a git-source binding. The returned boolean reports whether either of
+// them is present.
+func ResolveSource(config Configuration, bindingResolver BindingResolver, platformDir string) (Source, bool, error) {
+	if config.Source.URL != "" {
+		return config.Source, true, nil
+	}
+
+	bindings, err := bindingResolver.Resolve("git-source", "", platformDir)
+	if err != nil {
+		return Source{}, false, err
+	}
+
+	if len(bindings) == 0 {
+		return Source{}, false, nil
+	}
+
+	if len(bindings) > 1 {
+		return Source{}, false, fmt.Errorf("failed: found %d git-source bindings: please limit the bindings to one", len(bindings))
+	}
+
+	binding := bindings[0]
+	source := Source{Depth: config.Source.Depth}
+	for name, value := range map[string]*string{"url": &source.URL, "ref": &source.Ref, "commit": &source.Commit} {
+		entry, ok := binding.Entries[name]
+		if !ok {
+			continue
+		}
+
+		content, err := entry.ReadString()
+		if err != nil {
+			return Source{}, false, err
+		}
+
+		*value = strings.TrimSpace(content)
+	}
+
+	if entry, ok := binding.Entries["depth"]; ok {
+		content, err := entry.ReadString()
+		if err != nil {
+			return Source{}, false, err
+		}
+
+		source.Depth, err = parseDepth(strings.TrimSpace(content))
+		if err != nil {
+			return Source{}, false, fmt.Errorf("failed to parse depth of binding %s: %w", binding.Path, err)
+		}
+	}
+
+	if source.URL == "" {
+		return Source{}, false, fmt.Errorf("failed: binding %s does not have a url entry", binding.Path)
+	}
+
+	if source.Commit != "" {
+		source.Commit, err = parseCommit(source.Commit)
+		if err != nil {
+			return Source{}, false, fmt.Errorf("failed to parse commit of binding %s: %w", binding.Path, err)
+		}
+	}
+
+	return source, true, nil
+}
+
+// parseCommit returns the lower case form of a full SHA-1 or SHA-256 commit
+// SHA. An abbreviated SHA is rejected, as it would match other commits and
+// cannot be fetched from most servers.
+func parseCommit(value string) (string, error) {
+	commit := strings.ToLower(value)
+	if len(commit) != 40 && len(commit) != 64 {
+		return "", fmt.Errorf("%q is not a full commit SHA", value)
+	}
+
+	if _, err := hex.DecodeString(commit); err != nil {
+		return "", fmt.Errorf("%q is not a full commit SHA", value)
+	}
+
+	return commit, nil
+}
+
+func parseDepth(value string) (int, error) {
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 0 {
+		return 0, fmt.Errorf("%q is not a number of commits", value)
+	}
+
+	return depth, nil
+}
+
+// CloneSource checks out the source repository into dir and returns the SHA
+// of the checked out commit. Only the requested ref, or commit, is fetched so
+// that a shallow clone stays small. The fetch goes through the global git
+// config, so the configured credentials apply to it.
+func CloneSource(executable Executable, logger scribe.Emitter, dir string, source Source) (string, error) {
+	_, err := os.Stat(filepath.Join(dir, ".git"))
+	if err == nil {
+		return "", fmt.Errorf("failed to clone %s: %s already contains a git repository", RedactCredentials(source.URL), dir)
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to clone %s: %w", RedactCredentials(source.URL), err)
+	}
+
+	_, err = runGit(executable, logger, dir, "init", "--quiet")
+	if err != nil {
+		return "", err
+	}
+
+	_, err = runGit(executable, logger, dir, "remote", "add", "origin", source.URL)
+	if err != nil {
+		return "", err
+	}
+
+	refspec := source.Ref
+	if refspec == "" {
+		refspec = source.Commit
+	}
+	if refspec == "" {
+		refspec = "HEAD"
+	}
+
+	args := []string{"fetch", "--no-tags"}
+	if source.Depth > 0 {
+		args = append(args, fmt.Sprintf("--depth=%d", source.Depth))
+	}
+	args = append(args, "origin", refspec)
+
+	_, err = runRemoteGit(executable, logger, dir, args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s from %s: %w", refspec, RedactCredentials(source.URL), err)
+	}
+
+	// The credentials in the URL are only needed for the fetch and must not
+	// be left in .git/config
+	if url := stripCredentials(source.URL); url != source.URL {
+		_, err = runGit(executable, logger, dir, "remote", "set-url", "origin", url)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// A fetched branch or tag is recreated locally so that the metadata
+	// reports it, anything else is checked out as a detached HEAD
+	kind, name, err := fetchedRef(filepath.Join(dir, ".git", "FETCH_HEAD"))
+	if err != nil {
+		return "", err
+	}
+
+	switch kind {
+	case "branch":
+		_, err = runGit(executable, logger, dir, "checkout", "--quiet", "-B", name, "FETCH_HEAD")
+	case "tag":
+		_, err = runGit(executable, logger, dir, "tag", name, "FETCH_HEAD")
+		if err == nil {
+			_, err = runGit(executable, logger, dir, "checkout", "--quiet", "--detach", name)
+		}
+	default:
+		_, err = runGit(executable, logger, dir, "checkout", "--quiet", "--detach", "FETCH_HEAD")
+	}
+	if err != nil {
+		return "", err
+	}
+
+	commit, err := runGit(executable, logger, dir, "rev-parse", "HEAD")
+	if err != nil {
+		return "", err
+	}
+
+	if source.Commit != "" && commit != source.Commit {
+		return "", fmt.Errorf("failed to verify source: %s resolved to %s and not to %s", refspec, commit, source.Commit)
+	}
+
+	return commit, nil
+}
+
+// runRemoteGit executes a git command that talks to a remote and returns its
+// standard output. The command must never block on a terminal prompt when
+// there are no credentials for the remote, and its output is redacted as the
+// remote URL may embed them.
+func runRemoteGit(executable Executable, logger scribe.Emitter, dir string, args ...string) (string, error) {
+	stdout := bytes.NewBuffer(nil)
+	stderr := bytes.NewBuffer(nil)
+	err := executable.Execute(pexec.Execution{
+		Args:   args,
+		Dir:    dir,
+		Env:    append(os.Environ(), "GIT_TERMINAL_PROMPT=0"),
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	if err != nil {
+		logger.Detail(RedactCredentials(stdout.String() + stderr.String()))
+		return "", err
+	}
+
+	return stdout.String(), nil
+}
+
+// fetchedRef returns the kind ("branch" or "tag") and name of the ref
+// recorded in the first line of FETCH_HEAD, e.g.
+// "<sha>\t\tbranch 'main' of https://example.com/repo". Both are empty when a
+// commit or HEAD was fetched.
+func fetchedRef(path string) (string, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read FETCH_HEAD: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	if !scanner.Scan() {
+		return "", "", scanner.Err()
+	}
+
+	fields := strings.SplitN(scanner.Text(), "\t", 3)
+	if len(fields) < 3 {
+		return "", "", nil
+	}
+
+	for _, kind := range []string{"branch", "tag"} {
+		if rest, ok := strings.CutPrefix(fields[2], kind+" '"); ok {
+			name, _, found := strings.Cut(rest, "'")
+			if found {
+				return kind, name, nil
+			}
+		}
+	}
+
+	return "", "", nil
+}
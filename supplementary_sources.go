@@ -0,0 +1,249 @@
+package git
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/packit/v2/fs"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+const (
+	// LayerNameGitSources is the name of the cache layer that the checkouts
+	// of the supplementary sources are kept in between builds.
+	LayerNameGitSources = "git-sources"
+)
+
+// SupplementarySource is a repository that is cloned into a subdirectory of
+// the application source, e.g. shared protobuf definitions or themes.
+type SupplementarySource struct {
+	// URL is the location of the repository.
+	URL string `toml:"url" json:"url"`
+
+	// Ref is the branch or tag that is checked out. The default branch of the
+	// repository is checked out when it is empty.
+	Ref string `toml:"ref,omitempty" json:"ref,omitempty"`
+
+	// Commit pins the checked out commit by its full SHA. Once cloned, it is
+	// the SHA of the checked out commit.
+	Commit string `toml:"commit,omitempty" json:"commit,omitempty"`
+
+	// Path is the subdirectory of the application source that the
+	// repository is checked out into.
+	Path string `toml:"path" json:"path"`
+}
+
+// ResolveSupplementarySources returns the repositories listed in the sources
+// entry of every git-sources binding.
+func ResolveSupplementarySources(bindingResolver BindingResolver, platformDir string) ([]SupplementarySource, error) {
+	bindings, err := bindingResolver.Resolve("git-sources", "", platformDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []SupplementarySource
+	paths := map[string]string{}
+	for _, binding := range bindings {
+		entry, ok := binding.Entries["sources"]
+		if !ok {
+			return nil, fmt.Errorf("failed: binding %s does not have a sources entry", binding.Path)
+		}
+
+		content, err := entry.ReadString()
+		if err != nil {
+			return nil, err
+		}
+
+		var document struct {
+			Sources []SupplementarySource `toml:"sources"`
+		}
+		_, err = toml.Decode(content, &document)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse sources of binding %s: %w", binding.Path, err)
+		}
+
+		for _, source := range document.Sources {
+			if source.URL == "" || source.Path == "" {
+				return nil, fmt.Errorf("failed: every source in binding %s requires a url and a path", binding.Path)
+			}
+
+			if source.Commit != "" {
+				source.Commit, err = parseCommit(source.Commit)
+				if err != nil {
+					return nil, fmt.Errorf("failed to parse commit of binding %s: %w", binding.Path, err)
+				}
+			}
+
+			source.Path = filepath.Clean(source.Path)
+			if !filepath.IsLocal(source.Path) {
+				return nil, fmt.Errorf("failed: path %q of binding %s is not within the application directory", source.Path, binding.Path)
+			}
+
+			if other, ok := paths[source.Path]; ok {
+				return nil, fmt.Errorf("failed: path %q is used by %s and %s: please clone every source into its own path", source.Path, RedactCredentials(other), RedactCredentials(source.URL))
+			}
+			paths[source.Path] = source.URL
+
+			sources = append(sources, source)
+		}
+	}
+
+	return sources, nil
+}
+
+// CloneSupplementarySources checks out every source into its path below
+// workingDir and returns the sources with the SHA of their checked out
+// commits. The checkouts are kept in cacheDir keyed by URL and commit, so a
+// source is only fetched when its ref has moved. Checkouts that are no longer
+// used are removed from cacheDir.
+func CloneSupplementarySources(executable Executable, logger scribe.Emitter, workingDir, cacheDir string, sources []SupplementarySource) ([]SupplementarySource, error) {
+	used := map[string]bool{}
+
+	var cloned []SupplementarySource
+	for _, source := range sources {
+		// A pinned commit is checked against the ref before a cached
+		// checkout is reused, just like a new clone is
+		commit := source.Commit
+		if commit == "" || source.Ref != "" {
+			var err error
+			commit, err = resolveRemoteRef(executable, logger, workingDir, source.URL, source.Ref)
+			if err != nil {
+				return nil, err
+			}
+
+			if source.Commit != "" && commit != source.Commit {
+				return nil, fmt.Errorf("failed to verify source: %s resolved to %s and not to %s", source.Ref, commit, source.Commit)
+			}
+		}
+
+		key, err := sha256Hex(func(w io.Writer) error {
+			_, err := fmt.Fprintf(w, "%s\n%s", source.URL, commit)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		used[key] = true
+
+		checkout := filepath.Join(cacheDir, key)
+		_, err = os.Stat(checkout)
+		if err == nil {
+			commit, err = runGit(executable, logger, checkout, "rev-parse", "HEAD")
+			if err != nil {
+				return nil, err
+			}
+
+			logger.Subprocess("%s: reusing %s", source.Path, commit)
+		} else if os.IsNotExist(err) {
+			err = os.MkdirAll(checkout, os.ModePerm)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create checkout of %s: %w", RedactCredentials(source.URL), err)
+			}
+
+			commit, err = CloneSource(executable, logger, checkout, Source{URL: source.URL, Ref: source.Ref, Commit: commit, Depth: 1})
+			if err != nil {
+				// A partial checkout must not be reused by the next build
+				_ = os.RemoveAll(checkout)
+				return nil, err
+			}
+
+			logger.Subprocess("%s: cloned %s", source.Path, commit)
+		} else {
+			return nil, fmt.Errorf("failed to read checkout of %s: %w", RedactCredentials(source.URL), err)
+		}
+
+		err = copyCheckout(checkout, filepath.Join(workingDir, source.Path))
+		if err != nil {
+			return nil, err
+		}
+
+		cloned = append(cloned, SupplementarySource{
+			URL:    RedactCredentials(source.URL),
+			Ref:    source.Ref,
+			Commit: commit,
+			Path:   source.Path,
+		})
+	}
+
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", cacheDir, err)
+	}
+
+	for _, entry := range entries {
+		if !used[entry.Name()] {
+			err = os.RemoveAll(filepath.Join(cacheDir, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to remove unused checkout: %w", err)
+			}
+		}
+	}
+
+	return cloned, nil
+}
+
+// resolveRemoteRef returns the commit that ref, or HEAD when it is empty,
+// points at in the remote repository. The ref is resolved in the same order
+// as git fetch does, and tags are peeled to their commit.
+func resolveRemoteRef(executable Executable, logger scribe.Emitter, dir, url, ref string) (string, error) {
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	// The peeled tags are only listed when they are asked for
+	output, err := runRemoteGit(executable, logger, dir, "ls-remote", url, ref, ref+"^{}")
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s in %s: %w", ref, RedactCredentials(url), err)
+	}
+
+	refs := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		commit, name, found := strings.Cut(line, "\t")
+		if found {
+			refs[name] = commit
+		}
+	}
+
+	for _, name := range []string{ref + "^{}", ref, "refs/tags/" + ref + "^{}", "refs/tags/" + ref, "refs/heads/" + ref} {
+		if commit, ok := refs[name]; ok {
+			return commit, nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to resolve %s in %s: no such ref", ref, RedactCredentials(url))
+}
+
+// copyCheckout copies the files of a checkout, without its .git directory,
+// to destination, which must not contain any files.
+func copyCheckout(checkout, destination string) error {
+	err := os.MkdirAll(destination, os.ModePerm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", destination, err)
+	}
+
+	if !fs.IsEmptyDir(destination) {
+		return fmt.Errorf("failed: %s already contains files", destination)
+	}
+
+	entries, err := os.ReadDir(checkout)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", checkout, err)
+	}
+
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+
+		err = fs.Copy(filepath.Join(checkout, entry.Name()), filepath.Join(destination, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to copy %s: %w", entry.Name(), err)
+		}
+	}
+
+	return nil
+}
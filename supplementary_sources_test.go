@@ -0,0 +1,348 @@
+package git_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/git/fakes"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/paketo-buildpacks/packit/v2/servicebindings"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testSupplementarySources(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		bindingResolver *fakes.BindingResolver
+		platformDir     string
+	)
+
+	it.Before(func() {
+		var err error
+		platformDir, err = os.MkdirTemp("", "platform")
+		Expect(err).NotTo(HaveOccurred())
+
+		bindingResolver = &fakes.BindingResolver{}
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(platformDir)).To(Succeed())
+	})
+
+	context("ResolveSupplementarySources", func() {
+		var bind func(content string)
+
+		it.Before(func() {
+			bind = func(content string) {
+				Expect(os.WriteFile(filepath.Join(platformDir, "sources"), []byte(content), 0644)).To(Succeed())
+				bindingResolver.ResolveCall.Returns.BindingSlice = []servicebindings.Binding{
+					{
+						Path:    platformDir,
+						Entries: map[string]*servicebindings.Entry{"sources": servicebindings.NewEntry(filepath.Join(platformDir, "sources"))},
+					},
+				}
+			}
+		})
+
+		it("returns the listed sources", func() {
+			bind(`
+[[sources]]
+url = "https://example.com/protos.git"
+ref = "v1.2.3"
+path = "./third_party/protos/"
+
+[[sources]]
+url = "https://example.com/theme.git"
+commit = "abcdef0123456789abcdef0123456789abcdef01"
+path = "theme"
+`)
+
+			sources, err := git.ResolveSupplementarySources(bindingResolver, "some-platform")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sources).To(Equal([]git.SupplementarySource{
+				{URL: "https://example.com/protos.git", Ref: "v1.2.3", Path: "third_party/protos"},
+				{URL: "https://example.com/theme.git", Commit: "abcdef0123456789abcdef0123456789abcdef01", Path: "theme"},
+			}))
+
+			Expect(bindingResolver.ResolveCall.Receives.Typ).To(Equal("git-sources"))
+			Expect(bindingResolver.ResolveCall.Receives.PlatformDir).To(Equal("some-platform"))
+		})
+
+		context("failure cases", func() {
+			context("when the binding does not have a sources entry", func() {
+				it.Before(func() {
+					bindingResolver.ResolveCall.Returns.BindingSlice = []servicebindings.Binding{{Path: "some-path"}}
+				})
+
+				it("returns an error", func() {
+					_, err := git.ResolveSupplementarySources(bindingResolver, platformDir)
+					Expect(err).To(MatchError("failed: binding some-path does not have a sources entry"))
+				})
+			})
+
+			context("when the sources are not valid TOML", func() {
+				it("returns an error", func() {
+					bind("[[sources")
+					_, err := git.ResolveSupplementarySources(bindingResolver, platformDir)
+					Expect(err).To(MatchError(ContainSubstring("failed to parse sources of binding")))
+				})
+			})
+
+			context("when a source does not have a path", func() {
+				it("returns an error", func() {
+					bind("[[sources]]\nurl = \"https://example.com/protos.git\"\n")
+					_, err := git.ResolveSupplementarySources(bindingResolver, platformDir)
+					Expect(err).To(MatchError(ContainSubstring("requires a url and a path")))
+				})
+			})
+
+			context("when a commit is not a full SHA", func() {
+				it("returns an error", func() {
+					bind("[[sources]]\nurl = \"https://example.com/protos.git\"\ncommit = \"a\"\npath = \"protos\"\n")
+					_, err := git.ResolveSupplementarySources(bindingResolver, platformDir)
+					Expect(err).To(MatchError(ContainSubstring(`"a" is not a full commit SHA`)))
+				})
+			})
+
+			context("when a path is outside of the application directory", func() {
+				it("returns an error", func() {
+					bind("[[sources]]\nurl = \"https://example.com/protos.git\"\npath = \"../protos\"\n")
+					_, err := git.ResolveSupplementarySources(bindingResolver, platformDir)
+					Expect(err).To(MatchError(ContainSubstring(`path "../protos" of binding`)))
+				})
+			})
+
+			context("when two sources use the same path", func() {
+				it("returns an error", func() {
+					bind("[[sources]]\nurl = \"https://example.com/a.git\"\npath = \"protos\"\n[[sources]]\nurl = \"https://example.com/b.git\"\npath = \"protos/\"\n")
+					_, err := git.ResolveSupplementarySources(bindingResolver, platformDir)
+					Expect(err).To(MatchError(ContainSubstring(`path "protos" is used by https://example.com/a.git and https://example.com/b.git`)))
+				})
+			})
+		})
+	})
+
+	context("CloneSupplementarySources", func() {
+		var (
+			home       string
+			workingDir string
+			cacheDir   string
+			upstream   string
+			url        string
+			first      string
+			second     string
+
+			executable *fakes.Executable
+			commands   []string
+			logger     scribe.Emitter
+			run        func(dir string, args ...string) string
+		)
+
+		it.Before(func() {
+			gitPath, err := exec.LookPath("git")
+			Expect(err).NotTo(HaveOccurred())
+
+			home, err = os.MkdirTemp("", "home")
+			Expect(err).NotTo(HaveOccurred())
+
+			workingDir, err = os.MkdirTemp("", "working-dir")
+			Expect(err).NotTo(HaveOccurred())
+
+			cacheDir, err = os.MkdirTemp("", "cache")
+			Expect(err).NotTo(HaveOccurred())
+
+			env := append(os.Environ(),
+				"HOME="+home,
+				"GIT_CONFIG_NOSYSTEM=1",
+				"GIT_AUTHOR_NAME=some-author",
+				"GIT_AUTHOR_EMAIL=author@example.com",
+				"GIT_COMMITTER_NAME=some-author",
+				"GIT_COMMITTER_EMAIL=author@example.com",
+			)
+
+			run = func(dir string, args ...string) string {
+				command := exec.Command(gitPath, args...)
+				command.Dir = dir
+				command.Env = env
+				output, err := command.CombinedOutput()
+				Expect(err).NotTo(HaveOccurred(), string(output))
+				return strings.TrimSpace(string(output))
+			}
+
+			upstream = filepath.Join(home, "upstream")
+			Expect(os.MkdirAll(upstream, os.ModePerm)).To(Succeed())
+			run(upstream, "init", "--quiet", "--initial-branch=main")
+
+			Expect(os.WriteFile(filepath.Join(upstream, "some.proto"), []byte("first"), 0644)).To(Succeed())
+			run(upstream, "add", "some.proto")
+			run(upstream, "commit", "--quiet", "-m", "first")
+			run(upstream, "tag", "--annotate", "-m", "release", "v1.0.0")
+			first = run(upstream, "rev-parse", "HEAD")
+
+			Expect(os.WriteFile(filepath.Join(upstream, "some.proto"), []byte("second"), 0644)).To(Succeed())
+			run(upstream, "commit", "--quiet", "-am", "second")
+			second = run(upstream, "rev-parse", "HEAD")
+
+			run(home, "clone", "--quiet", "--bare", upstream, filepath.Join(home, "protos.git"))
+			url = "file://" + filepath.Join(home, "protos.git")
+
+			commands = nil
+			executable = &fakes.Executable{}
+			executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+				commands = append(commands, execution.Args[0])
+				if execution.Env == nil {
+					execution.Env = os.Environ()
+				}
+				execution.Env = append(execution.Env, "HOME="+home, "GIT_CONFIG_NOSYSTEM=1")
+
+				return pexec.NewExecutable("git").Execute(execution)
+			}
+
+			logger = scribe.NewEmitter(bytes.NewBuffer(nil))
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(home)).To(Succeed())
+			Expect(os.RemoveAll(workingDir)).To(Succeed())
+			Expect(os.RemoveAll(cacheDir)).To(Succeed())
+		})
+
+		it("clones the sources into the working directory without their .git directory", func() {
+			sources, err := git.CloneSupplementarySources(executable, logger, workingDir, cacheDir, []git.SupplementarySource{
+				{URL: url, Ref: "main", Path: "protos/latest"},
+				{URL: url, Ref: "v1.0.0", Path: "protos/stable"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sources).To(Equal([]git.SupplementarySource{
+				{URL: url, Ref: "main", Commit: second, Path: "protos/latest"},
+				{URL: url, Ref: "v1.0.0", Commit: first, Path: "protos/stable"},
+			}))
+
+			content, err := os.ReadFile(filepath.Join(workingDir, "protos", "latest", "some.proto"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("second"))
+
+			content, err = os.ReadFile(filepath.Join(workingDir, "protos", "stable", "some.proto"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(Equal("first"))
+
+			Expect(filepath.Join(workingDir, "protos", "latest", ".git")).NotTo(BeAnExistingFile())
+		})
+
+		context("when the sources were cloned by a previous build", func() {
+			it.Before(func() {
+				_, err := git.CloneSupplementarySources(executable, logger, workingDir, cacheDir, []git.SupplementarySource{
+					{URL: url, Ref: "main", Path: "protos"},
+					{URL: url, Ref: "v1.0.0", Path: "stale"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.RemoveAll(workingDir)).To(Succeed())
+				Expect(os.MkdirAll(workingDir, os.ModePerm)).To(Succeed())
+				commands = nil
+			})
+
+			it("reuses the checkout and removes the unused ones", func() {
+				sources, err := git.CloneSupplementarySources(executable, logger, workingDir, cacheDir, []git.SupplementarySource{
+					{URL: url, Ref: "main", Path: "protos"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sources[0].Commit).To(Equal(second))
+				Expect(commands).NotTo(ContainElement("fetch"))
+
+				Expect(filepath.Join(workingDir, "protos", "some.proto")).To(BeARegularFile())
+
+				entries, err := os.ReadDir(cacheDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(entries).To(HaveLen(1))
+			})
+
+			context("when the ref has moved", func() {
+				it.Before(func() {
+					Expect(os.WriteFile(filepath.Join(upstream, "some.proto"), []byte("third"), 0644)).To(Succeed())
+					run(upstream, "commit", "--quiet", "-am", "third")
+					run(upstream, "push", "--quiet", url, "main")
+				})
+
+				it("fetches the new commit", func() {
+					sources, err := git.CloneSupplementarySources(executable, logger, workingDir, cacheDir, []git.SupplementarySource{
+						{URL: url, Ref: "main", Path: "protos"},
+					})
+					Expect(err).NotTo(HaveOccurred())
+					Expect(sources[0].Commit).NotTo(Equal(second))
+					Expect(commands).To(ContainElement("fetch"))
+
+					content, err := os.ReadFile(filepath.Join(workingDir, "protos", "some.proto"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(string(content)).To(Equal("third"))
+				})
+
+				it("does not reuse a checkout of a commit that the ref no longer points at", func() {
+					_, err := git.CloneSupplementarySources(executable, logger, workingDir, cacheDir, []git.SupplementarySource{
+						{URL: url, Ref: "main", Commit: second, Path: "protos"},
+					})
+					Expect(err).To(MatchError(ContainSubstring("failed to verify source: main resolved to")))
+					Expect(filepath.Join(workingDir, "protos")).NotTo(BeAnExistingFile())
+				})
+			})
+		})
+
+		context("when the commit is pinned", func() {
+			it("does not resolve the ref", func() {
+				sources, err := git.CloneSupplementarySources(executable, logger, workingDir, cacheDir, []git.SupplementarySource{
+					{URL: url, Commit: first, Path: "protos"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(sources[0].Commit).To(Equal(first))
+				Expect(commands).NotTo(ContainElement("ls-remote"))
+			})
+		})
+
+		context("failure cases", func() {
+			context("when the ref does not exist", func() {
+				it("returns an error", func() {
+					_, err := git.CloneSupplementarySources(executable, logger, workingDir, cacheDir, []git.SupplementarySource{
+						{URL: url, Ref: "missing", Path: "protos"},
+					})
+					Expect(err).To(MatchError(ContainSubstring("failed to resolve missing in " + url + ": no such ref")))
+				})
+			})
+
+			context("when the pinned commit does not exist", func() {
+				it("removes the partial checkout and returns an error", func() {
+					_, err := git.CloneSupplementarySources(executable, logger, workingDir, cacheDir, []git.SupplementarySource{
+						{URL: url, Commit: strings.Repeat("0", 40), Path: "protos"},
+					})
+					Expect(err).To(MatchError(ContainSubstring("failed to fetch")))
+
+					entries, err := os.ReadDir(cacheDir)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(entries).To(BeEmpty())
+				})
+			})
+
+			context("when the path already contains files", func() {
+				it.Before(func() {
+					Expect(os.MkdirAll(filepath.Join(workingDir, "protos"), os.ModePerm)).To(Succeed())
+					Expect(os.WriteFile(filepath.Join(workingDir, "protos", "some-file"), nil, 0644)).To(Succeed())
+				})
+
+				it("returns an error", func() {
+					_, err := git.CloneSupplementarySources(executable, logger, workingDir, cacheDir, []git.SupplementarySource{
+						{URL: url, Ref: "main", Path: "protos"},
+					})
+					Expect(err).To(MatchError(ContainSubstring("protos already contains files")))
+				})
+			})
+		})
+	})
+}
@@ -0,0 +1,284 @@
+package git
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+)
+
+// TreeAttestation compares the application source as it was built to the
+// tree that HEAD records for it.
+type TreeAttestation struct {
+	// Tree is the git tree object ID of the files in the application
+	// directory that are tracked or not ignored.
+	Tree string `toml:"tree" json:"tree"`
+
+	// HeadTree is the git tree object ID of the application directory at
+	// HEAD.
+	HeadTree string `toml:"head_tree" json:"head_tree"`
+
+	// Matches reports whether the application source is exactly the tree of
+	// HEAD.
+	Matches bool `toml:"matches" json:"matches"`
+}
+
+// Variables returns the environment variables that describe the trees.
+func (a TreeAttestation) Variables() map[string]string {
+	return map[string]string{
+		"GIT_TREE":      a.Tree,
+		"GIT_HEAD_TREE": a.HeadTree,
+	}
+}
+
+// treeNode is a directory of the application source, its entries are either
+// files with their mode and object ID or subdirectories.
+type treeNode struct {
+	files map[string]treeFile
+	dirs  map[string]*treeNode
+}
+
+type treeFile struct {
+	mode string
+	id   []byte
+}
+
+// AttestTree computes the tree object ID that git would record for the files
+// below dir, the tracked files and the untracked files that .gitignore does
+// not exclude, as they are on disk. The files are hashed without applying
+// any of the git filters, e.g. line ending conversions, so the ID describes
+// the files that are built. Submodules and nested repositories are recorded
+// by the commit that they have checked out. The ID is computed with the
+// object format of the repository and compared to the tree of dir at HEAD.
+func AttestTree(executable Executable, logger scribe.Emitter, dir string) (TreeAttestation, error) {
+	head, err := runGit(executable, logger, dir, "rev-parse", "HEAD:./")
+	if err != nil {
+		return TreeAttestation{}, err
+	}
+
+	var newHash func() hash.Hash
+	switch len(head) {
+	case 2 * sha1.Size:
+		newHash = sha1.New
+	case 2 * sha256.Size:
+		newHash = sha256.New
+	default:
+		return TreeAttestation{}, fmt.Errorf("failed to attest tree: %q is not an object ID", head)
+	}
+
+	tracked, err := runGitRaw(executable, logger, dir, "ls-files", "-z", "--stage")
+	if err != nil {
+		return TreeAttestation{}, err
+	}
+
+	// The commits of the submodules are only known to the index
+	gitlinks := map[string]string{}
+	var paths []string
+	for _, entry := range strings.Split(tracked, "\x00") {
+		info, path, ok := strings.Cut(entry, "\t")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(info)
+		if len(fields) == 3 && fields[0] == "160000" {
+			gitlinks[path] = fields[1]
+		}
+		paths = append(paths, path)
+	}
+
+	untracked, err := runGitRaw(executable, logger, dir, "ls-files", "-z", "--others", "--exclude-standard")
+	if err != nil {
+		return TreeAttestation{}, err
+	}
+	for _, path := range strings.Split(untracked, "\x00") {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	root := &treeNode{}
+	for _, path := range paths {
+		file, ok, err := hashTreeFile(executable, logger, dir, path, gitlinks[path], newHash)
+		if err != nil {
+			return TreeAttestation{}, err
+		}
+
+		if ok {
+			root.add(strings.Split(path, "/"), file)
+		}
+	}
+
+	id, err := root.hash(newHash)
+	if err != nil {
+		return TreeAttestation{}, err
+	}
+
+	tree := hex.EncodeToString(id)
+	return TreeAttestation{
+		Tree:     tree,
+		HeadTree: head,
+		Matches:  tree == head,
+	}, nil
+}
+
+// hashTreeFile returns the tree entry of the file at path. The returned
+// boolean is false when the file is not on disk or cannot be recorded in a
+// tree, e.g. a tracked file that was replaced by a directory.
+func hashTreeFile(executable Executable, logger scribe.Emitter, dir, path, gitlink string, newHash func() hash.Hash) (treeFile, bool, error) {
+	full := filepath.Join(dir, path)
+	info, err := os.Lstat(full)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return treeFile{}, false, nil
+		}
+		return treeFile{}, false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	switch mode := info.Mode(); {
+	case mode.IsDir():
+		commit := gitlink
+		if commit == "" && strings.HasSuffix(path, "/") {
+			commit, err = runGit(executable, logger, full, "rev-parse", "HEAD")
+			if err != nil {
+				return treeFile{}, false, err
+			}
+		}
+
+		if commit == "" {
+			return treeFile{}, false, nil
+		}
+
+		id, err := hex.DecodeString(commit)
+		if err != nil {
+			return treeFile{}, false, fmt.Errorf("failed to read commit of %s: %w", path, err)
+		}
+
+		return treeFile{mode: "160000", id: id}, true, nil
+
+	case mode&os.ModeSymlink != 0:
+		target, err := os.Readlink(full)
+		if err != nil {
+			return treeFile{}, false, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		id, err := hashObject(newHash, "blob", int64(len(target)), strings.NewReader(target))
+		if err != nil {
+			return treeFile{}, false, err
+		}
+
+		return treeFile{mode: "120000", id: id}, true, nil
+
+	case mode.IsRegular():
+		file, err := os.Open(full)
+		if err != nil {
+			return treeFile{}, false, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		defer file.Close()
+
+		id, err := hashObject(newHash, "blob", info.Size(), file)
+		if err != nil {
+			return treeFile{}, false, fmt.Errorf("failed to hash %s: %w", path, err)
+		}
+
+		// Like git, only the executable bit of the owner is recorded
+		if mode&0100 != 0 {
+			return treeFile{mode: "100755", id: id}, true, nil
+		}
+
+		return treeFile{mode: "100644", id: id}, true, nil
+	}
+
+	return treeFile{}, false, nil
+}
+
+func (n *treeNode) add(names []string, file treeFile) {
+	// Nested repositories are listed with a trailing slash
+	if names[len(names)-1] == "" {
+		names = names[:len(names)-1]
+	}
+
+	if len(names) == 1 {
+		if n.files == nil {
+			n.files = map[string]treeFile{}
+		}
+		n.files[names[0]] = file
+		return
+	}
+
+	if n.dirs == nil {
+		n.dirs = map[string]*treeNode{}
+	}
+
+	child, ok := n.dirs[names[0]]
+	if !ok {
+		child = &treeNode{}
+		n.dirs[names[0]] = child
+	}
+
+	child.add(names[1:], file)
+}
+
+// hash returns the object ID of the tree. The entries are sorted the way git
+// sorts them, with the names of subdirectories compared as if they ended in
+// a slash.
+func (n *treeNode) hash(newHash func() hash.Hash) ([]byte, error) {
+	type entry struct {
+		name string
+		key  string
+		mode string
+		id   []byte
+	}
+
+	var entries []entry
+	for name, file := range n.files {
+		entries = append(entries, entry{name: name, key: name, mode: file.mode, id: file.id})
+	}
+
+	for name, dir := range n.dirs {
+		id, err := dir.hash(newHash)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, entry{name: name, key: name + "/", mode: "40000", id: id})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].key < entries[j].key
+	})
+
+	content := bytes.NewBuffer(nil)
+	for _, entry := range entries {
+		fmt.Fprintf(content, "%s %s\x00", entry.mode, entry.name)
+		content.Write(entry.id)
+	}
+
+	return hashObject(newHash, "tree", int64(content.Len()), content)
+}
+
+// hashObject returns the ID of the git object of the given type and content.
+func hashObject(newHash func() hash.Hash, kind string, size int64, content io.Reader) ([]byte, error) {
+	h := newHash()
+	fmt.Fprintf(h, "%s %d\x00", kind, size)
+
+	n, err := io.Copy(h, content)
+	if err != nil {
+		return nil, err
+	}
+
+	if n != size {
+		return nil, fmt.Errorf("read %d bytes instead of %d", n, size)
+	}
+
+	return h.Sum(nil), nil
+}
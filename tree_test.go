@@ -0,0 +1,215 @@
+package git_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/paketo-buildpacks/git"
+	"github.com/paketo-buildpacks/git/fakes"
+	"github.com/paketo-buildpacks/packit/v2/pexec"
+	"github.com/paketo-buildpacks/packit/v2/scribe"
+	"github.com/sclevine/spec"
+
+	. "github.com/onsi/gomega"
+)
+
+func testTree(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		home       string
+		workingDir string
+		executable *fakes.Executable
+		logger     scribe.Emitter
+		run        func(dir string, args ...string) string
+	)
+
+	it.Before(func() {
+		gitPath, err := exec.LookPath("git")
+		Expect(err).NotTo(HaveOccurred())
+
+		home, err = os.MkdirTemp("", "home")
+		Expect(err).NotTo(HaveOccurred())
+
+		workingDir, err = os.MkdirTemp("", "working-dir")
+		Expect(err).NotTo(HaveOccurred())
+
+		env := append(os.Environ(),
+			"HOME="+home,
+			"GIT_CONFIG_NOSYSTEM=1",
+			"GIT_AUTHOR_NAME=some-author",
+			"GIT_AUTHOR_EMAIL=author@example.com",
+			"GIT_COMMITTER_NAME=some-author",
+			"GIT_COMMITTER_EMAIL=author@example.com",
+		)
+
+		run = func(dir string, args ...string) string {
+			command := exec.Command(gitPath, args...)
+			command.Dir = dir
+			command.Env = env
+			output, err := command.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(output))
+			return strings.TrimSpace(string(output))
+		}
+
+		executable = &fakes.Executable{}
+		executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+			execution.Env = append(os.Environ(), "HOME="+home, "GIT_CONFIG_NOSYSTEM=1")
+			return pexec.NewExecutable("git").Execute(execution)
+		}
+
+		logger = scribe.NewEmitter(bytes.NewBuffer(nil))
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(home)).To(Succeed())
+		Expect(os.RemoveAll(workingDir)).To(Succeed())
+	})
+
+	commit := func(args ...string) {
+		Expect(os.MkdirAll(filepath.Join(workingDir, "sub", "dir"), os.ModePerm)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, ".gitignore"), []byte("*.log\n"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "some-file"), []byte("some-content"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "some-file.txt"), []byte("other-content"), 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "run.sh"), []byte("#!/bin/sh\n"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "sub", "dir", "file"), nil, 0644)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workingDir, "sub-file"), nil, 0644)).To(Succeed())
+		Expect(os.Symlink("some-file", filepath.Join(workingDir, "link"))).To(Succeed())
+
+		run(workingDir, append([]string{"init", "--quiet"}, args...)...)
+		run(workingDir, "add", "--all")
+		run(workingDir, "commit", "--quiet", "-m", "first")
+
+		Expect(os.WriteFile(filepath.Join(workingDir, "debug.log"), []byte("ignored"), 0644)).To(Succeed())
+	}
+
+	// writeTree is the tree that git add --all would record
+	writeTree := func() string {
+		run(workingDir, "add", "--all")
+		return run(workingDir, "write-tree")
+	}
+
+	context("AttestTree", func() {
+		context("when the application source is the tree of HEAD", func() {
+			it.Before(func() {
+				commit()
+			})
+
+			it("matches the tree of HEAD", func() {
+				attestation, err := git.AttestTree(executable, logger, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(attestation).To(Equal(git.TreeAttestation{
+					Tree:     run(workingDir, "rev-parse", "HEAD^{tree}"),
+					HeadTree: run(workingDir, "rev-parse", "HEAD^{tree}"),
+					Matches:  true,
+				}))
+			})
+
+			context("when the application is in a subdirectory", func() {
+				it("matches the tree of the subdirectory", func() {
+					attestation, err := git.AttestTree(executable, logger, filepath.Join(workingDir, "sub"))
+					Expect(err).NotTo(HaveOccurred())
+					Expect(attestation.Tree).To(Equal(run(workingDir, "rev-parse", "HEAD:sub")))
+					Expect(attestation.Matches).To(BeTrue())
+				})
+			})
+		})
+
+		context("when the application source has changed", func() {
+			it.Before(func() {
+				commit()
+
+				Expect(os.WriteFile(filepath.Join(workingDir, "some-file"), []byte("changed"), 0644)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(workingDir, "sub", "new-file"), []byte("new"), 0644)).To(Succeed())
+				Expect(os.Remove(filepath.Join(workingDir, "sub-file"))).To(Succeed())
+				Expect(os.Chmod(filepath.Join(workingDir, "run.sh"), 0644)).To(Succeed())
+			})
+
+			it("records the tree of the files on disk", func() {
+				attestation, err := git.AttestTree(executable, logger, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(attestation.HeadTree).To(Equal(run(workingDir, "rev-parse", "HEAD^{tree}")))
+				Expect(attestation.Tree).To(Equal(writeTree()))
+				Expect(attestation.Matches).To(BeFalse())
+			})
+		})
+
+		context("when there is a nested repository", func() {
+			it.Before(func() {
+				commit()
+
+				nested := filepath.Join(workingDir, "nested")
+				Expect(os.MkdirAll(nested, os.ModePerm)).To(Succeed())
+				run(nested, "init", "--quiet")
+				run(nested, "commit", "--quiet", "--allow-empty", "-m", "nested")
+			})
+
+			it("records the checked out commit", func() {
+				attestation, err := git.AttestTree(executable, logger, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(attestation.Tree).To(Equal(writeTree()))
+				Expect(attestation.Matches).To(BeFalse())
+			})
+		})
+
+		context("when the repository uses SHA-256 object IDs", func() {
+			it.Before(func() {
+				commit("--object-format=sha256")
+			})
+
+			it("matches the tree of HEAD", func() {
+				attestation, err := git.AttestTree(executable, logger, workingDir)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(attestation.Tree).To(HaveLen(64))
+				Expect(attestation.Tree).To(Equal(run(workingDir, "rev-parse", "HEAD^{tree}")))
+				Expect(attestation.Matches).To(BeTrue())
+			})
+		})
+
+		context("failure cases", func() {
+			context("when git fails", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(pexec.Execution) error {
+						return errors.New("some-error")
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := git.AttestTree(executable, logger, workingDir)
+					Expect(err).To(MatchError("failed to execute 'git rev-parse HEAD:./': some-error"))
+				})
+			})
+
+			context("when the tree of HEAD is not an object ID", func() {
+				it.Before(func() {
+					executable.ExecuteCall.Stub = func(execution pexec.Execution) error {
+						fmt.Fprint(execution.Stdout, "some-output\n")
+						return nil
+					}
+				})
+
+				it("returns an error", func() {
+					_, err := git.AttestTree(executable, logger, workingDir)
+					Expect(err).To(MatchError(`failed to attest tree: "some-output" is not an object ID`))
+				})
+			})
+		})
+	})
+
+	context("TreeAttestation", func() {
+		context("Variables", func() {
+			it("returns both trees", func() {
+				Expect(git.TreeAttestation{Tree: "some-tree", HeadTree: "head-tree"}.Variables()).To(Equal(map[string]string{
+					"GIT_TREE":      "some-tree",
+					"GIT_HEAD_TREE": "head-tree",
+				}))
+			})
+		})
+	})
+}